@@ -0,0 +1,117 @@
+package main
+
+import "math"
+
+const (
+	// moveSpeed is the camera speed in units per second.
+	moveSpeed = 4
+	// turnSpeed is the camera turn in radians per second.
+	turnSpeed = 1.5
+	// mouseSensitivity is the camera turn in radians per pixel of cursor
+	// motion.
+	mouseSensitivity = 0.005
+)
+
+// maxPitch keeps the camera from looking straight up or down, where yaw and
+// roll would turn around the same axis.
+const maxPitch = math.Pi/2 - 0.01
+
+// camera is a free-look camera with six degrees of freedom. Yaw turns
+// around the world's y axis, pitch looks up and down and roll tilts the view
+// around the line of sight. Positive yaw turns right, positive pitch looks
+// up.
+type camera struct {
+	pos              vec3d
+	yaw, pitch, roll float64
+}
+
+func newCamera(pos vec3d, yaw float64) camera {
+	return camera{pos: pos, yaw: yaw}
+}
+
+// orientation rotates camera space, looking down +z with +y up, into world
+// space.
+func (c *camera) orientation() quat {
+	return quatFromEuler(-c.pitch, c.yaw, c.roll)
+}
+
+// turn adds to the camera's angles and clamps its pitch.
+func (c *camera) turn(yaw, pitch, roll float64) {
+	c.yaw = math.Remainder(c.yaw+yaw, 2*math.Pi)
+	c.pitch = math.Max(-maxPitch, math.Min(maxPitch, c.pitch+pitch))
+	c.roll = math.Remainder(c.roll+roll, 2*math.Pi)
+}
+
+// forward, right and up return the camera's unit axes in world space. right
+// points to the right edge of the rendered image, which is camera space -x
+// because the projection mirrors x.
+func (c *camera) forward() vec3d {
+	q := c.orientation()
+	return q.Rotate(&vec3d{0, 0, 1, 0})
+}
+
+func (c *camera) right() vec3d {
+	q := c.orientation()
+	return q.Rotate(&vec3d{-1, 0, 0, 0})
+}
+
+func (c *camera) up() vec3d {
+	q := c.orientation()
+	return q.Rotate(&vec3d{0, 1, 0, 0})
+}
+
+// move moves the camera along its own axes.
+func (c *camera) move(forward, right, up float64) {
+	vForward := c.forward()
+	vRight := c.right()
+	vUp := c.up()
+	vForward = vForward.Mul(forward)
+	vRight = vRight.Mul(right)
+	vUp = vUp.Mul(up)
+
+	c.pos = c.pos.Add(&vForward)
+	c.pos = c.pos.Add(&vRight)
+	c.pos = c.pos.Add(&vUp)
+}
+
+// lerpCamera interpolates between two poses, turning the short way around.
+func lerpCamera(c1, c2 *camera, t float64) camera {
+	lerp := func(a, b float64) float64 {
+		return a + (b-a)*t
+	}
+	turn := func(a, b float64) float64 {
+		return a + math.Remainder(b-a, 2*math.Pi)*t
+	}
+
+	return camera{
+		pos:   vec3d{lerp(c1.pos.x, c2.pos.x), lerp(c1.pos.y, c2.pos.y), lerp(c1.pos.z, c2.pos.z), 1},
+		yaw:   turn(c1.yaw, c2.yaw),
+		pitch: lerp(c1.pitch, c2.pitch),
+		roll:  turn(c1.roll, c2.roll),
+	}
+}
+
+// viewMatrix transforms world space into the camera's view space. The up
+// vector handed to matrixPointAt is the camera's own, so roll tilts the view.
+func (c *camera) viewMatrix() mat4x4 {
+	vForward := c.forward()
+	vUp := c.up()
+	target := c.pos.Add(&vForward)
+
+	matCamera := matrixPointAt(&c.pos, &target, &vUp)
+	return matrixQuickInverse(&matCamera)
+}
+
+// update turns and moves the camera by the actions of a frame that took dt
+// seconds.
+func (c *camera) update(a *actions, dt float64) {
+	turn := turnSpeed * dt
+	c.turn(
+		a[actionYaw]*turn+a[actionLookX]*mouseSensitivity,
+		a[actionPitch]*turn+a[actionLookY]*mouseSensitivity,
+		a[actionRoll]*turn,
+	)
+
+	speed := moveSpeed * dt
+	c.move(a[actionMoveForward]*speed, a[actionMoveRight]*speed, a[actionAscend]*speed)
+}
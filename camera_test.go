@@ -0,0 +1,111 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+var cameraPoses = []camera{
+	{pos: vec3d{0, 0, 0, 1}},
+	{pos: vec3d{1, 2, 3, 1}, yaw: 0.7},
+	{pos: vec3d{-4, 0.5, 2, 1}, yaw: -2, pitch: 0.4},
+	{pos: vec3d{0, -1, 8, 1}, yaw: 3, pitch: -1.2, roll: 0.9},
+}
+
+func TestCameraAxes(t *testing.T) {
+	for _, c := range cameraPoses {
+		forward, right, up := c.forward(), c.right(), c.up()
+		for _, v := range []vec3d{forward, right, up} {
+			if math.Abs(v.Length()-1) > matrixEpsilon {
+				t.Errorf("%+v: axis %v is not a unit vector", c, v)
+			}
+		}
+		if d := forward.DotProduct(&right); math.Abs(d) > matrixEpsilon {
+			t.Errorf("%+v: forward·right = %g, want 0", c, d)
+		}
+		if d := forward.DotProduct(&up); math.Abs(d) > matrixEpsilon {
+			t.Errorf("%+v: forward·up = %g, want 0", c, d)
+		}
+		if d := right.DotProduct(&up); math.Abs(d) > matrixEpsilon {
+			t.Errorf("%+v: right·up = %g, want 0", c, d)
+		}
+	}
+}
+
+func TestCameraViewMatrix(t *testing.T) {
+	for _, c := range cameraPoses {
+		matView := c.viewMatrix()
+
+		if got := matView.matrixMultiplyVector(&c.pos); !vectorsEqual(got, vec3d{0, 0, 0, 1}) {
+			t.Errorf("%+v: camera position maps to %v, want the origin", c, got)
+		}
+
+		forward, up := c.forward(), c.up()
+		ahead := c.pos.Add(&forward)
+		above := c.pos.Add(&up)
+		if got := matView.matrixMultiplyVector(&ahead); !vectorsEqual(got, vec3d{0, 0, 1, 1}) {
+			t.Errorf("%+v: forward maps to %v, want +z", c, got)
+		}
+		if got := matView.matrixMultiplyVector(&above); !vectorsEqual(got, vec3d{0, 1, 0, 1}) {
+			t.Errorf("%+v: up maps to %v, want +y", c, got)
+		}
+	}
+}
+
+func TestCameraMatchesFixedUpView(t *testing.T) {
+	for _, yaw := range []float64{0, 0.5, -2, math.Pi} {
+		c := newCamera(vec3d{1, 2, 3, 1}, yaw)
+		got := c.viewMatrix()
+
+		vLookDirection := lookDirection(yaw)
+		want := matrixMakeView(&c.pos, &vLookDirection)
+		if !matricesEqual(&got, &want) {
+			t.Errorf("yaw %g: got %v, want %v", yaw, got, want)
+		}
+	}
+}
+
+func TestCameraTurn(t *testing.T) {
+	tests := []struct {
+		name             string
+		yaw, pitch, roll float64
+		forward, up      vec3d
+	}{
+		{"pitch up", 0, 0.5, 0, vec3d{0, math.Sin(0.5), math.Cos(0.5), 0}, vec3d{0, math.Cos(0.5), -math.Sin(0.5), 0}},
+		{"pitch clamped", 0, 10, 0, vec3d{0, math.Sin(maxPitch), math.Cos(maxPitch), 0}, vec3d{0, math.Cos(maxPitch), -math.Sin(maxPitch), 0}},
+		{"pitch clamped down", 0, -10, 0, vec3d{0, -math.Sin(maxPitch), math.Cos(maxPitch), 0}, vec3d{0, math.Cos(maxPitch), math.Sin(maxPitch), 0}},
+		{"yaw right", math.Pi / 2, 0, 0, vec3d{-1, 0, 0, 0}, vec3d{0, 1, 0, 0}},
+		{"roll keeps forward", 0, 0, math.Pi / 2, vec3d{0, 0, 1, 0}, vec3d{-1, 0, 0, 0}},
+	}
+
+	for _, test := range tests {
+		c := newCamera(vec3d{0, 0, 0, 1}, 0)
+		c.turn(test.yaw, test.pitch, test.roll)
+		if got := c.forward(); !vectorsEqual(got, test.forward) {
+			t.Errorf("%s: forward %v, want %v", test.name, got, test.forward)
+		}
+		if got := c.up(); !vectorsEqual(got, test.up) {
+			t.Errorf("%s: up %v, want %v", test.name, got, test.up)
+		}
+	}
+}
+
+func TestCameraMove(t *testing.T) {
+	c := newCamera(vec3d{1, 2, 3, 1}, math.Pi/2)
+	c.move(2, 0, 0)
+	if want := (vec3d{-1, 2, 3, 1}); !vectorsEqual(c.pos, want) {
+		t.Errorf("forward: got %v, want %v", c.pos, want)
+	}
+
+	c = newCamera(vec3d{1, 2, 3, 1}, 0)
+	c.move(0, 1, 0)
+	if want := (vec3d{0, 2, 3, 1}); !vectorsEqual(c.pos, want) {
+		t.Errorf("right: got %v, want %v", c.pos, want)
+	}
+
+	c.turn(0, 0, math.Pi/2)
+	c.move(0, 0, 1)
+	if want := (vec3d{-1, 2, 3, 1}); !vectorsEqual(c.pos, want) {
+		t.Errorf("up after roll: got %v, want %v", c.pos, want)
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+// clipPlane is a plane in homogeneous clip space. A point is inside it when
+// a*x + b*y + c*z + d*w >= 0.
+type clipPlane [4]float64
+
+// frustumPlanes bound the view volume after the projection matrix:
+// -w <= x <= w, -w <= y <= w and 0 <= z <= w. matrixMakeProjection maps the
+// near plane to z = 0 and the far plane to z = w, so clipping against them
+// honours fNear and fFar.
+var frustumPlanes = [6]clipPlane{
+	{0, 0, 1, 0},  // near
+	{0, 0, -1, 1}, // far
+	{1, 0, 0, 1},  // left
+	{-1, 0, 0, 1}, // right
+	{0, 1, 0, 1},  // bottom
+	{0, -1, 0, 1}, // top
+}
+
+func (c *clipPlane) distance(p *vec3d) float64 {
+	return c[0]*p.x + c[1]*p.y + c[2]*p.z + c[3]*p.w
+}
+
+// clipQueueSize bounds the triangles a triangle can turn into while it is
+// clipped against the frustum planes, each plane at most doubles them.
+const clipQueueSize = 1 << len(frustumPlanes)
+
+// triangleQueue is a fixed size ring buffer of triangles waiting to be
+// clipped, so clipping does not allocate.
+type triangleQueue struct {
+	buf        [clipQueueSize]triangle
+	head, size int
+}
+
+func (q *triangleQueue) reset() {
+	q.head, q.size = 0, 0
+}
+
+func (q *triangleQueue) len() int {
+	return q.size
+}
+
+// push copies t to the back of the queue.
+func (q *triangleQueue) push(t *triangle) {
+	q.buf[(q.head+q.size)%clipQueueSize] = *t
+	q.size++
+}
+
+// pop returns the triangle at the front of the queue. It stays valid until
+// clipQueueSize more triangles have been pushed.
+func (q *triangleQueue) pop() *triangle {
+	t := &q.buf[q.head]
+	q.head = (q.head + 1) % clipQueueSize
+	q.size--
+	return t
+}
+
+// clipFrustum clips the clip space triangle t against the frustum planes and
+// leaves the pieces in queue. Triangles entirely outside the view volume are
+// dropped. The sides of the view volume are widened by guardBand, so that
+// triangles crossing the screen edges but staying inside the guard band are
+// passed on whole and left to the rasterizer to scissor. A guardBand of 1
+// clips at the screen edges.
+func clipFrustum(t *triangle, guardBand float64, queue *triangleQueue) {
+	queue.reset()
+	for p := range frustumPlanes {
+		plane := &frustumPlanes[p]
+		if plane.distance(&t.p[0]) < 0 && plane.distance(&t.p[1]) < 0 && plane.distance(&t.p[2]) < 0 {
+			return
+		}
+	}
+
+	clipped := [2]triangle{}
+	queue.push(t)
+
+	for p := range frustumPlanes {
+		plane := frustumPlanes[p]
+		if p >= 2 {
+			// left, right, bottom and top
+			plane[3] *= guardBand
+		}
+		for n := queue.len(); n > 0; n-- {
+			test := queue.pop()
+			trisToAdd := triangleClipAgainstPlane(&plane, test, &clipped[0], &clipped[1])
+			for w := 0; w < trisToAdd; w++ {
+				queue.push(&clipped[w])
+			}
+		}
+	}
+}
+
+// triangleClipAgainstPlane clips in_tri against plane and writes the
+// resulting zero, one or two triangles to out_tri1 and out_tri2. New
+// vertices are interpolated linearly in clip space, which is correct for all
+// four coordinates, the texture coordinates and the varyings before the
+// perspective divide.
+func triangleClipAgainstPlane(plane *clipPlane, in_tri, out_tri1, out_tri2 *triangle) int {
+	var d [3]float64
+	var inside, outside [3]int
+	nInsidePointCount, nOutsidePointCount := 0, 0
+	for n := range in_tri.p {
+		d[n] = plane.distance(&in_tri.p[n])
+		if d[n] >= 0 {
+			inside[nInsidePointCount] = n
+			nInsidePointCount++
+		} else {
+			outside[nOutsidePointCount] = n
+			nOutsidePointCount++
+		}
+	}
+
+	// intersect writes the point where the edge from corner a to corner b
+	// crosses the plane to corner n of out
+	intersect := func(out *triangle, n, a, b int) {
+		t := d[a] / (d[a] - d[b])
+		pa, pb := &in_tri.p[a], &in_tri.p[b]
+		out.p[n] = vec3d{
+			x: pa.x + t*(pb.x-pa.x),
+			y: pa.y + t*(pb.y-pa.y),
+			z: pa.z + t*(pb.z-pa.z),
+			w: pa.w + t*(pb.w-pa.w),
+		}
+		ta, tb := &in_tri.t[a], &in_tri.t[b]
+		out.t[n] = vec2d{
+			u: ta.u + t*(tb.u-ta.u),
+			v: ta.v + t*(tb.v-ta.v),
+			w: ta.w + t*(tb.w-ta.w),
+		}
+		va, vb := &in_tri.v[a], &in_tri.v[b]
+		for k := range out.v[n] {
+			out.v[n][k] = va[k] + t*(vb[k]-va[k])
+		}
+	}
+	// keep copies corner a of in_tri to corner n of out
+	keep := func(out *triangle, n, a int) {
+		out.p[n] = in_tri.p[a]
+		out.t[n] = in_tri.t[a]
+		out.v[n] = in_tri.v[a]
+	}
+
+	switch nInsidePointCount {
+	case 0:
+		// the whole triangle is outside
+		return 0
+	case 3:
+		*out_tri1 = *in_tri
+		return 1
+	case 1:
+		// two corners are outside, the triangle gets smaller
+		*out_tri1 = *in_tri
+		keep(out_tri1, 0, inside[0])
+		intersect(out_tri1, 1, inside[0], outside[0])
+		intersect(out_tri1, 2, inside[0], outside[1])
+		return 1
+	default:
+		// one corner is outside, the remaining quad is split into two
+		// triangles
+		*out_tri1 = *in_tri
+		*out_tri2 = *in_tri
+		keep(out_tri1, 0, inside[0])
+		keep(out_tri1, 1, inside[1])
+		intersect(out_tri1, 2, inside[0], outside[0])
+		keep(out_tri2, 0, inside[1])
+		out_tri2.p[1], out_tri2.t[1], out_tri2.v[1] = out_tri1.p[2], out_tri1.t[2], out_tri1.v[2]
+		intersect(out_tri2, 2, inside[1], outside[0])
+		return 2
+	}
+}
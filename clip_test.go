@@ -0,0 +1,121 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestClipFrustum(t *testing.T) {
+	tests := []struct {
+		name   string
+		p      [3]vec3d
+		pieces int
+		// area is the screen area in NDC the pieces cover, 0 to skip
+		area float64
+	}{
+		{"inside", [3]vec3d{{0, 0, 0.5, 1}, {0.5, 0, 0.5, 1}, {0, 0.5, 0.5, 1}}, 1, 0.125},
+		{"behind the near plane", [3]vec3d{{0, 0, -0.5, 1}, {0.5, 0, -0.5, 1}, {0, 0.5, -0.1, 1}}, 0, 0},
+		{"beyond the far plane", [3]vec3d{{0, 0, 2, 1}, {0.5, 0, 2, 1}, {0, 0.5, 3, 1}}, 0, 0},
+		{"one corner in front of the near plane", [3]vec3d{{0, 0, 0.5, 1}, {0.5, 0, -0.5, 1}, {0, 0.5, -0.5, 1}}, 1, 0},
+		{"one corner behind the near plane", [3]vec3d{{0, 0, -0.5, 1}, {0.5, 0, 0.5, 1}, {0, 0.5, 0.5, 1}}, 2, 0},
+		{"crossing the far plane", [3]vec3d{{0, 0, 0.5, 1}, {0.5, 0, 1.5, 1}, {0, 0.5, 0.5, 1}}, 2, 0},
+		{"left of the screen", [3]vec3d{{-3, 0, 0.5, 1}, {-2, 0, 0.5, 1}, {-3, 0.5, 0.5, 1}}, 0, 0},
+		// covers the whole view volume with corners far outside every side
+		{"huge", [3]vec3d{{-1e6, -1e6, 0.5, 1}, {1e6, -1e6, 0.5, 1}, {0, 1e6, 0.5, 1}}, 8, 4},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var queue triangleQueue
+			in := triangle{p: test.p}
+			clipFrustum(&in, 1, &queue)
+			if queue.len() != test.pieces {
+				t.Fatalf("got %d triangles, want %d", queue.len(), test.pieces)
+			}
+			area := 0.0
+			for queue.len() > 0 {
+				piece := queue.pop()
+				a, b, c := piece.p[0], piece.p[1], piece.p[2]
+				area += math.Abs((b.x-a.x)*(c.y-a.y)-(c.x-a.x)*(b.y-a.y)) / 2
+				for _, p := range piece.p {
+					for i, plane := range frustumPlanes {
+						if d := plane.distance(&p); d < -1e-9 {
+							t.Errorf("corner %v is %g outside plane %d", p, -d, i)
+						}
+					}
+				}
+			}
+			if test.area != 0 && math.Abs(area-test.area) > 1e-9 {
+				t.Errorf("pieces cover %g, want %g", area, test.area)
+			}
+		})
+	}
+}
+
+func TestTriangleClipAgainstPlaneInterpolates(t *testing.T) {
+	in := triangle{
+		p: [3]vec3d{{0, 0, -1, 1}, {0, 0, 1, 3}, {1, 0, 1, 3}},
+		t: UVs{{0, 0, 1}, {1, 0, 1}, {0, 1, 1}},
+	}
+	for n := range in.v {
+		for k := range in.v[n] {
+			in.v[n][k] = float64(10*n + k)
+		}
+	}
+	var out [2]triangle
+	if n := triangleClipAgainstPlane(&frustumPlanes[0], &in, &out[0], &out[1]); n != 2 {
+		t.Fatalf("got %d triangles, want 2", n)
+	}
+
+	// the edge from corner 1 to corner 0 crosses z = 0 halfway
+	got := out[0].p[2]
+	want := vec3d{0, 0, 0, 2}
+	if math.Abs(got.x-want.x)+math.Abs(got.z-want.z)+math.Abs(got.w-want.w) > 1e-12 {
+		t.Errorf("got intersection %v, want %v", got, want)
+	}
+	if uv := out[0].t[2]; math.Abs(uv.u-0.5) > 1e-12 || uv.v != 0 {
+		t.Errorf("got uv %v, want {0.5 0 1}", uv)
+	}
+	for k, got := range out[0].v[2] {
+		if want := float64(5 + k); math.Abs(got-want) > 1e-12 {
+			t.Errorf("varying %d: got %g, want %g", k, got, want)
+		}
+	}
+	// corners that are kept carry their varyings along
+	if out[0].v[0] != in.v[1] || out[0].v[1] != in.v[2] {
+		t.Errorf("got varyings %v, want those of the inside corners", out[0].v[:2])
+	}
+}
+
+func TestClipFrustumGuardBand(t *testing.T) {
+	tests := []struct {
+		name   string
+		p      [3]vec3d
+		pieces int
+	}{
+		{"inside", [3]vec3d{{0, 0, 0.5, 1}, {0.5, 0, 0.5, 1}, {0, 0.5, 0.5, 1}}, 1},
+		{"crossing the screen edge", [3]vec3d{{0, 0, 0.5, 1}, {3, 0, 0.5, 1}, {0, 0.5, 0.5, 1}}, 1},
+		{"crossing two screen edges", [3]vec3d{{-3, -3, 0.5, 1}, {3, -3, 0.5, 1}, {0, 3, 0.5, 1}}, 1},
+		{"outside the screen but inside the guard band", [3]vec3d{{2, 0, 0.5, 1}, {3, 0, 0.5, 1}, {2, 0.5, 0.5, 1}}, 0},
+		{"crossing the guard band", [3]vec3d{{0, 0, 0.5, 1}, {8, 0, 0.5, 1}, {0, 0.5, 0.5, 1}}, 2},
+		{"crossing the near plane", [3]vec3d{{0, 0, -0.5, 1}, {0.5, 0, 0.5, 1}, {0, 0.5, 0.5, 1}}, 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var queue triangleQueue
+			in := triangle{p: test.p}
+			clipFrustum(&in, 4, &queue)
+			if queue.len() != test.pieces {
+				t.Fatalf("got %d triangles, want %d", queue.len(), test.pieces)
+			}
+			for queue.len() > 0 {
+				for _, p := range queue.pop().p {
+					if math.Abs(p.x) > 4*p.w+1e-9 || math.Abs(p.y) > 4*p.w+1e-9 {
+						t.Errorf("corner %v is outside the guard band", p)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import "time"
+
+const (
+	// simulationStep is the fixed length of one simulation step.
+	simulationStep = time.Second / 60
+	// maxFrameTime bounds the time simulated per frame. After a longer
+	// stall the simulation falls behind instead of running ever more steps
+	// to catch up.
+	maxFrameTime = 250 * time.Millisecond
+)
+
+// clock tells the time since an arbitrary start. Tests use a fake clock to
+// control time.
+type clock interface {
+	now() time.Duration
+}
+
+// wallClock is the monotonic time since it was made.
+type wallClock struct {
+	start time.Time
+}
+
+func newWallClock() wallClock {
+	return wallClock{start: time.Now()}
+}
+
+func (c wallClock) now() time.Duration {
+	return time.Since(c.start)
+}
+
+// fixedStep runs a simulation in steps of a fixed length, however long the
+// frames are. Time not yet simulated accumulates until it makes up a step.
+type fixedStep struct {
+	clock       clock
+	step        time.Duration
+	last        time.Duration
+	accumulator time.Duration
+}
+
+func newFixedStep(c clock, step time.Duration) *fixedStep {
+	return &fixedStep{clock: c, step: step, last: c.now()}
+}
+
+// advance returns how many steps to simulate for the time passed since the
+// last call.
+func (s *fixedStep) advance() int {
+	now := s.clock.now()
+	s.accumulator += min(now-s.last, maxFrameTime)
+	s.last = now
+
+	steps := int(s.accumulator / s.step)
+	s.accumulator -= time.Duration(steps) * s.step
+	return steps
+}
+
+// alpha returns how far the present is between the last simulated step and
+// the next one, in [0, 1]. Rendering interpolates between the last two
+// simulated states by it.
+func (s *fixedStep) alpha() float64 {
+	pending := s.accumulator + s.clock.now() - s.last
+	return min(1, float64(pending)/float64(s.step))
+}
+
+// seconds returns the length of a step in seconds.
+func (s *fixedStep) seconds() float64 {
+	return s.step.Seconds()
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// fakeClock is a clock that only moves when told to.
+type fakeClock struct {
+	t time.Duration
+}
+
+func (c *fakeClock) now() time.Duration {
+	return c.t
+}
+
+func TestFixedStepAdvance(t *testing.T) {
+	tests := []struct {
+		frame time.Duration
+		steps int
+		alpha float64
+	}{
+		{0, 0, 0},
+		{5 * time.Millisecond, 0, 0.5},
+		{5 * time.Millisecond, 1, 0},
+		{25 * time.Millisecond, 2, 0.5},
+		{5 * time.Millisecond, 1, 0},
+		// a stall only simulates maxFrameTime
+		{time.Second, 25, 0},
+	}
+
+	c := &fakeClock{}
+	s := newFixedStep(c, 10*time.Millisecond)
+	for i, test := range tests {
+		c.t += test.frame
+		if steps := s.advance(); steps != test.steps {
+			t.Errorf("frame %d: got %d steps, want %d", i, steps, test.steps)
+		}
+		if alpha := s.alpha(); math.Abs(alpha-test.alpha) > 1e-9 {
+			t.Errorf("frame %d: got alpha %g, want %g", i, alpha, test.alpha)
+		}
+	}
+
+	// alpha keeps moving with the clock between frames
+	c.t += 4 * time.Millisecond
+	if alpha := s.alpha(); math.Abs(alpha-0.4) > 1e-9 {
+		t.Errorf("between frames: got alpha %g, want 0.4", alpha)
+	}
+}
+
+// simulate moves and turns a camera by the actions a in every frame, over
+// frames of the given lengths, and returns where it ends up.
+func simulate(frames []time.Duration, a actions) camera {
+	c := &fakeClock{}
+	s := newFixedStep(c, simulationStep)
+	cam := newCamera(vec3d{0, 0, 0, 1}, 0)
+
+	var pending actions
+	for _, frame := range frames {
+		c.t += frame
+		pending.accumulate(&a)
+		for steps := s.advance(); steps > 0; steps-- {
+			a := pending.take()
+			cam.update(&a, s.seconds())
+		}
+	}
+	return cam
+}
+
+func TestFixedStepIndependentOfFrameRate(t *testing.T) {
+	var steady, uneven []time.Duration
+	for i := 0; i < 120; i++ {
+		steady = append(steady, simulationStep)
+	}
+	// the same two seconds, in frames that do not line up with the steps
+	for i := 0; i < 60; i++ {
+		uneven = append(uneven, simulationStep/2, simulationStep*3/2)
+	}
+
+	held := actions{actionMoveForward: 1, actionYaw: 1}
+	want := simulate(steady, held)
+	if got := simulate(uneven, held); got != want {
+		t.Errorf("uneven frames: got %+v, want %+v", got, want)
+	}
+
+	// cursor motion is applied at the next step, so the path differs, but
+	// the same number of pixels turns the camera just as far
+	look := actions{actionLookX: 2}
+	got := simulate(uneven, look)
+	if want := 120 * 2 * mouseSensitivity; math.Abs(got.yaw-want) > 1e-9 {
+		t.Errorf("cursor: got yaw %g, want %g", got.yaw, want)
+	}
+}
+
+func TestActionsAccumulate(t *testing.T) {
+	var pending actions
+	pending.accumulate(&actions{actionMoveForward: 1, actionLookX: 3, actionFit: 1})
+	pending.accumulate(&actions{actionMoveForward: -1, actionLookX: 2})
+
+	want := actions{actionMoveForward: -1, actionLookX: 5, actionFit: 1}
+	if got := pending.take(); got != want {
+		t.Errorf("first step: got %v, want %v", got, want)
+	}
+	want = actions{actionMoveForward: -1}
+	if got := pending.take(); got != want {
+		t.Errorf("second step: got %v, want %v", got, want)
+	}
+}
+
+func TestLerpCamera(t *testing.T) {
+	c1 := camera{pos: vec3d{0, 0, 0, 1}, yaw: math.Pi - 0.1, pitch: 0.2}
+	c2 := camera{pos: vec3d{2, 4, -2, 1}, yaw: -math.Pi + 0.1, pitch: 0.4}
+
+	got := lerpCamera(&c1, &c2, 0.5)
+	if want := (vec3d{1, 2, -1, 1}); !vectorsEqual(got.pos, want) {
+		t.Errorf("pos: got %v, want %v", got.pos, want)
+	}
+	if math.Abs(math.Remainder(got.yaw-math.Pi, 2*math.Pi)) > 1e-9 {
+		t.Errorf("yaw: got %g, want to turn the short way to π", got.yaw)
+	}
+	if math.Abs(got.pitch-0.3) > 1e-9 {
+		t.Errorf("pitch: got %g, want 0.3", got.pitch)
+	}
+
+	if got := lerpCamera(&c1, &c2, 1); !vectorsEqual(got.pos, c2.pos) || math.Abs(math.Remainder(got.yaw-c2.yaw, 2*math.Pi)) > 1e-9 {
+		t.Errorf("t = 1: got %+v, want %+v", got, c2)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"image"
+	"math"
+)
+
+// subPixelBits is the number of fractional bits vertex positions are snapped
+// to before the edge functions are evaluated.
+const subPixelBits = 8
+
+const subPixel = 1 << subPixelBits
+
+// edgeTriangle fills the part of the projected triangle t inside clip by
+// evaluating its edge functions at every pixel center of its bounding box.
+// Positions are snapped to fixed point so that the edge functions are exact,
+// and pixels whose center lies on an edge are only drawn for top and left
+// edges.
+func (r *Renderer) edgeTriangle(t *triangle, tex TextureAtlas, clip image.Rectangle) {
+	var x, y [3]int64
+	for i := range t.p {
+		x[i] = int64(math.Round(t.p[i].x * subPixel))
+		y[i] = int64(math.Round(t.p[i].y * subPixel))
+	}
+
+	// order the vertices so the area is positive, which in screen space with
+	// y pointing down means clockwise
+	a, b, c := 0, 1, 2
+	area := (x[b]-x[a])*(y[c]-y[a]) - (y[b]-y[a])*(x[c]-x[a])
+	if area == 0 {
+		return
+	}
+	if area < 0 {
+		b, c = c, b
+		area = -area
+	}
+
+	minX := max(clip.Min.X, int(min(x[0], x[1], x[2])>>subPixelBits))
+	maxX := min(clip.Max.X-1, int(max(x[0], x[1], x[2])>>subPixelBits))
+	minY := max(clip.Min.Y, int(min(y[0], y[1], y[2])>>subPixelBits))
+	maxY := min(clip.Max.Y-1, int(max(y[0], y[1], y[2])>>subPixelBits))
+	if minX > maxX || minY > maxY {
+		return
+	}
+
+	// e0 is the edge opposite vertex a and weighs it, and so on
+	e0 := newEdge(x[b], y[b], x[c], y[c])
+	e1 := newEdge(x[c], y[c], x[a], y[a])
+	e2 := newEdge(x[a], y[a], x[b], y[b])
+
+	s := newSampler(tex)
+	g := triangleGradients(
+		t.p[0].x, t.p[0].y, t.t[0].u, t.t[0].v, t.t[0].w,
+		t.p[1].x, t.p[1].y, t.t[1].u, t.t[1].v, t.t[1].w,
+		t.p[2].x, t.p[2].y, t.t[2].u, t.t[2].v, t.t[2].w)
+
+	fArea := float64(area)
+	ta, tb, tc := &t.t[a], &t.t[b], &t.t[c]
+
+	px := int64(minX)<<subPixelBits + subPixel/2
+	py := int64(minY)<<subPixelBits + subPixel/2
+	w0Row := e0.at(px, py)
+	w1Row := e1.at(px, py)
+	w2Row := e2.at(px, py)
+
+	for i := minY; i <= maxY; i++ {
+		w0, w1, w2 := w0Row, w1Row, w2Row
+
+		for j := minX; j <= maxX; j++ {
+			if w0+e0.bias >= 0 && w1+e1.bias >= 0 && w2+e2.bias >= 0 {
+				l0 := float64(w0) / fArea
+				l1 := float64(w1) / fArea
+				l2 := float64(w2) / fArea
+
+				tex_w := l0*ta.w + l1*tb.w + l2*tc.w
+				if tex_w > r.depthBuffer[i*r.w+j] {
+					tex_u := l0*ta.u + l1*tb.u + l2*tc.u
+					tex_v := l0*ta.v + l1*tb.v + l2*tc.v
+
+					o := (i*r.w + j) * 4
+					s.sampleProjected(r.img.Pix[o:o+4], tex_u, tex_v, tex_w, &g)
+					r.depthBuffer[i*r.w+j] = tex_w
+				}
+			}
+
+			w0 += e0.stepX
+			w1 += e1.stepX
+			w2 += e2.stepX
+		}
+
+		w0Row += e0.stepY
+		w1Row += e1.stepY
+		w2Row += e2.stepY
+	}
+}
+
+// edge is the edge function of the directed edge from x0, y0 to x1, y1 in
+// fixed point. It is positive on the inside of a clockwise triangle.
+type edge struct {
+	x0, y0       int64
+	dx, dy       int64
+	stepX, stepY int64
+	// bias is added to the edge function before the inside test. It is -1
+	// for edges other than top and left ones, so pixel centers exactly on
+	// them are not drawn.
+	bias int64
+}
+
+func newEdge(x0, y0, x1, y1 int64) edge {
+	e := edge{
+		x0: x0,
+		y0: y0,
+		dx: x1 - x0,
+		dy: y1 - y0,
+	}
+	e.stepX = -e.dy * subPixel
+	e.stepY = e.dx * subPixel
+
+	topEdge := e.dy == 0 && e.dx > 0
+	leftEdge := e.dy < 0
+	if !topEdge && !leftEdge {
+		e.bias = -1
+	}
+	return e
+}
+
+func (e *edge) at(x, y int64) int64 {
+	return e.dx*(y-e.y0) - e.dy*(x-e.x0)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"testing"
+)
+
+// screenTriangle returns a projected triangle at depth 1 with the given
+// screen positions.
+func screenTriangle(x0, y0, x1, y1, x2, y2 float64) triangle {
+	return triangle{
+		p: [3]vec3d{{x0, y0, 0, 1}, {x1, y1, 0, 1}, {x2, y2, 0, 1}},
+		t: UVs{{0, 0, 1}, {0, 0, 1}, {0, 0, 1}},
+	}
+}
+
+// coverage rasterizes tris with the edge function rasterizer and returns how
+// often each pixel was written.
+func coverage(w, h int, tris ...triangle) []int {
+	counts := make([]int, w*h)
+	r := NewRenderer(w, h, matrixMakeIdentity())
+	for _, t := range tris {
+		r.Clear()
+		r.edgeTriangle(&t, whiteTexture, r.img.Bounds())
+		for i := range counts {
+			if r.img.Pix[i*4] == 255 {
+				counts[i]++
+			}
+		}
+	}
+	return counts
+}
+
+func TestEdgeTriangleSharedEdges(t *testing.T) {
+	tests := []struct {
+		name string
+		tris []triangle
+	}{
+		{"quad split along the diagonal", []triangle{
+			screenTriangle(3.3, 2.7, 40.2, 2.7, 40.2, 30.6),
+			screenTriangle(3.3, 2.7, 40.2, 30.6, 3.3, 30.6),
+		}},
+		{"quad split the other way, opposite winding", []triangle{
+			screenTriangle(3.3, 2.7, 3.3, 30.6, 40.2, 2.7),
+			screenTriangle(40.2, 2.7, 3.3, 30.6, 40.2, 30.6),
+		}},
+		{"fan around a vertex on a pixel center", []triangle{
+			screenTriangle(20.5, 20.5, 5, 5, 36, 5),
+			screenTriangle(20.5, 20.5, 36, 5, 36, 36),
+			screenTriangle(20.5, 20.5, 36, 36, 5, 36),
+			screenTriangle(20.5, 20.5, 5, 36, 5, 5),
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			counts := coverage(48, 48, test.tris...)
+
+			// the union of the triangles is an axis aligned rectangle,
+			// every pixel center inside it must be covered exactly once
+			minX, minY := test.tris[0].p[0].x, test.tris[0].p[0].y
+			maxX, maxY := minX, minY
+			for _, tri := range test.tris {
+				for _, p := range tri.p {
+					minX, maxX = min(minX, p.x), max(maxX, p.x)
+					minY, maxY = min(minY, p.y), max(maxY, p.y)
+				}
+			}
+
+			for y := 0; y < 48; y++ {
+				for x := 0; x < 48; x++ {
+					cx, cy := float64(x)+0.5, float64(y)+0.5
+					inside := cx >= minX && cx < maxX && cy >= minY && cy < maxY
+					want := 0
+					if inside {
+						want = 1
+					}
+					if got := counts[y*48+x]; got != want {
+						t.Fatalf("pixel %d,%d covered %d times, want %d", x, y, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestEdgeTriangleTopLeftRule(t *testing.T) {
+	// a right triangle whose edges run exactly through pixel centers: the
+	// left and top edges are filled, the diagonal is not
+	counts := coverage(8, 8, screenTriangle(0.5, 0.5, 4.5, 0.5, 0.5, 4.5))
+
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			want := 0
+			if x+y < 4 {
+				want = 1
+			}
+			if got := counts[y*8+x]; got != want {
+				t.Errorf("pixel %d,%d covered %d times, want %d", x, y, got, want)
+			}
+		}
+	}
+}
+
+func TestEdgeTriangleLargestGuardBand(t *testing.T) {
+	const w, h = 256, 256
+	// corners at the far edges of the largest guard band, with the long
+	// edge crossing the screen along x + y = w
+	e := (maxGuardBand(w, h) - 1) / 2 * w
+	counts := coverage(w, h, screenTriangle(-e, -e, e+w, -e, -e, e+w))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			want := 0
+			if x+y < w-1 {
+				want = 1
+			}
+			if counts[y*w+x] != want {
+				t.Fatalf("pixel %d, %d: written %d times, want %d", x, y, counts[y*w+x], want)
+			}
+		}
+	}
+}
@@ -0,0 +1,187 @@
+//go:build !headless
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"image/color"
+	"math"
+	"os"
+	"time"
+
+	_ "github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	_ "github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+var (
+	whiteImage = ebiten.NewImage(3, 3)
+)
+
+func init() {
+	whiteImage.Fill(color.White)
+}
+
+var (
+	w = int(256)
+	h = int(256)
+)
+
+type Game struct {
+	simulation
+	// matWorld and matView are the matrices of the last frame drawn
+	matWorld mat4x4
+	matView  mat4x4
+	bindings bindings
+	input    *ebitenInput
+}
+
+func (g *Game) Update() error {
+	g.input.update()
+	a := g.bindings.actions(g.input)
+	// the cursor turns the free-look camera only while it is captured
+	if !g.orbiting && ebiten.CursorMode() != ebiten.CursorModeCaptured {
+		a[actionLookX], a[actionLookY] = 0, 0
+	}
+	return g.update(&a)
+}
+
+func swap[T comparable](a, b *T) {
+	*a, *b = *b, *a
+}
+
+// 				screen.Set(int(j), int(i), sampleColour(tex_u/tex_w, tex_v/tex_w))
+
+func drawTriangle(screen *ebiten.Image, t *triangle) {
+	path := &vector.Path{}
+	path.MoveTo(t.X(0), t.Y(0))
+	path.LineTo(t.X(1), t.Y(1))
+	path.LineTo(t.X(2), t.Y(2))
+	path.Close()
+
+	vector.DrawFilledPath(screen, path, t, false, vector.FillRuleEvenOdd)
+	vector.StrokePath(screen, path, color.White, false, &vector.StrokeOptions{Width: 1})
+}
+
+func getColor(lum float64) (uint32, uint32, uint32, uint32) {
+	if lum < 0.1 {
+		lum = 0.1
+	}
+	v := 64 * 1024 * lum
+	return uint32(v), uint32(v), uint32(v), math.MaxUint32
+}
+
+func (g *Game) Draw(screen *ebiten.Image) {
+	t_start := time.Now()
+
+	// draw the state between the last two simulation steps that matches
+	// the present
+	alpha := g.timestep.alpha()
+	current := g.view()
+	c := lerpCamera(&g.previous, &current, alpha)
+	orientation := quatSlerp(&g.previousOrientation, &g.orientation, alpha)
+	g.matWorld = worldMatrix(&orientation)
+	g.matView = c.viewMatrix()
+
+	trianglesDrawn := g.renderer.Render(&g.mesh, &g.matWorld, &g.matView, &c.pos)
+	screen.WritePixels(g.renderer.Image().Pix)
+
+	t_duration := time.Since(t_start).Milliseconds()
+
+	ebitenutil.DebugPrint(screen, fmt.Sprintf("%.0f FPS, %d tris, %d rt, %s, guard band %gx", ebiten.ActualFPS(), trianglesDrawn, t_duration, g.renderer.Rasterizer(), g.renderer.GuardBand()))
+}
+
+func (g *Game) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
+	return w, h
+}
+
+// runWindow opens the interactive window. Its input can be recorded to a
+// file and replayed from one.
+func runWindow(args []string) (err error) {
+	flags := flag.NewFlagSet("3DGo", flag.ContinueOnError)
+	recordFile := flags.String("record", "", "record the input of every frame to this file")
+	replayFile := flags.String("replay", "", "replay the input recorded in this file before taking live input")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	ebiten.SetWindowSize(800, 800)
+	ebiten.SetWindowTitle("3D Engine")
+
+	textureAtlas := &TextureAtlasImpl{}
+	if err := textureAtlas.LoadTexture(); err != nil {
+		return err
+	}
+
+	cube := mesh{tex: textureAtlas}
+	cube.LoadCube()
+	// cube.Load("./cube4.obj", NewTextureLoader(nil))
+
+	projectionMatrix := defaultProjection(w, h, 90)
+
+	bindings, err := loadBindings(bindingsFile)
+	if err != nil {
+		return err
+	}
+	input, err := newEbitenInput(bindings)
+	if err != nil {
+		return err
+	}
+
+	g := &Game{
+		simulation: simulation{
+			mesh:                cube,
+			renderer:            NewRenderer(w, h, projectionMatrix),
+			cursor:              ebitenCursor{},
+			timestep:            newFixedStep(newWallClock(), simulationStep),
+			elapsedTime:         0,
+			fTheta:              0,
+			orientation:         quatIdentity(),
+			previousOrientation: quatIdentity(),
+			camera:              newCamera(vec3d{0.5, 0.5, 4.5, 1}, 0),
+		},
+		matWorld: matrixMakeIdentity(),
+		matView:  matrixMakeIdentity(),
+		bindings: bindings,
+		input:    input,
+	}
+	g.previous = g.camera
+
+	if *replayFile != "" {
+		file, err := os.Open(*replayFile)
+		if err != nil {
+			return err
+		}
+		g.replay, err = readRecording(file, *replayFile)
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	if *recordFile != "" {
+		file, err := os.Create(*recordFile)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if cerr := file.Close(); err == nil {
+				err = cerr
+			}
+		}()
+
+		if g.recorder, err = newRecorder(file); err != nil {
+			return err
+		}
+		defer func() {
+			if ferr := g.recorder.flush(); err == nil {
+				err = ferr
+			}
+		}()
+	}
+
+	return ebiten.RunGame(g)
+}
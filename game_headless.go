@@ -0,0 +1,11 @@
+//go:build headless
+
+package main
+
+import (
+	"errors"
+)
+
+func runWindow(args []string) error {
+	return errors.New("built with the headless tag, only the render command is available")
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// action is something the player can do, independent of the keys or mouse
+// buttons bound to it.
+type action int
+
+const (
+	// Axes. Their value is the sum of the bound inputs' values times the
+	// bindings' scales.
+	actionMoveForward action = iota
+	actionMoveRight
+	actionAscend
+	actionYaw
+	actionPitch
+	actionRoll
+	// actionLookX and actionLookY are the cursor motion in pixels, positive
+	// to look right and up
+	actionLookX
+	actionLookY
+	// actionZoom is positive to move the orbit camera closer
+	actionZoom
+	// actionOrbit and actionPan are held to drag the orbit camera
+	actionOrbit
+	actionPan
+
+	// Triggers. They are 1 in the frame one of their inputs is pressed and 0
+	// otherwise.
+	actionFit
+	actionToggleOrbit
+	actionToggleRasterizer
+	actionToggleGuardBand
+	actionCaptureCursor
+	actionReleaseCursor
+
+	numActions
+)
+
+var actionNames = [numActions]string{
+	actionMoveForward:      "MoveForward",
+	actionMoveRight:        "MoveRight",
+	actionAscend:           "Ascend",
+	actionYaw:              "Yaw",
+	actionPitch:            "Pitch",
+	actionRoll:             "Roll",
+	actionLookX:            "LookX",
+	actionLookY:            "LookY",
+	actionZoom:             "Zoom",
+	actionOrbit:            "Orbit",
+	actionPan:              "Pan",
+	actionFit:              "Fit",
+	actionToggleOrbit:      "ToggleOrbit",
+	actionToggleRasterizer: "ToggleRasterizer",
+	actionToggleGuardBand:  "ToggleGuardBand",
+	actionCaptureCursor:    "CaptureCursor",
+	actionReleaseCursor:    "ReleaseCursor",
+}
+
+func (a action) String() string {
+	if a < 0 || a >= numActions {
+		return fmt.Sprintf("action(%d)", int(a))
+	}
+	return actionNames[a]
+}
+
+func (a action) trigger() bool {
+	return a >= actionFit
+}
+
+// impulse reports whether the value of a is an amount per frame, as for
+// triggers and cursor motion, rather than a state that holds.
+func (a action) impulse() bool {
+	return a.trigger() || a == actionLookX || a == actionLookY || a == actionZoom
+}
+
+// actions holds the value of every action in one frame.
+type actions [numActions]float64
+
+// accumulate adds the actions of a frame to the actions collected for the
+// next simulation step. Impulses add up, held axes take their latest value.
+func (a *actions) accumulate(next *actions) {
+	for i := range a {
+		switch {
+		case action(i).trigger():
+			a[i] = max(a[i], next[i])
+		case action(i).impulse():
+			a[i] += next[i]
+		default:
+			a[i] = next[i]
+		}
+	}
+}
+
+// take returns the actions for one simulation step and clears the impulses,
+// so the following steps do not repeat them.
+func (a *actions) take() actions {
+	taken := *a
+	for i := range a {
+		if action(i).impulse() {
+			a[i] = 0
+		}
+	}
+	return taken
+}
+
+// triggered reports whether the trigger a fired.
+func (a *actions) triggered(t action) bool {
+	return a[t] != 0
+}
+
+// inputState is the state of the keyboard and mouse in one frame. Inputs
+// are named as in the bindings file. Tests drive the actions with a fake
+// instead of ebiten.
+type inputState interface {
+	// value returns 1 while a key or mouse button is held and 0 otherwise,
+	// and the motion since the last frame for mouse axes.
+	value(input string) float64
+	// justPressed reports whether a key or mouse button went down in this
+	// frame.
+	justPressed(input string) bool
+}
+
+// binding maps an input to an action. The input's value is multiplied by
+// scale, which inverts an axis when negative.
+type binding struct {
+	action action
+	input  string
+	scale  float64
+	// filename and line locate the binding for errors about its input
+	filename string
+	line     int
+}
+
+type bindings []binding
+
+// actions evaluates the bindings against the inputs of one frame.
+func (b bindings) actions(in inputState) actions {
+	var a actions
+	for _, binding := range b {
+		if binding.action.trigger() {
+			if in.justPressed(binding.input) {
+				a[binding.action] = 1
+			}
+			continue
+		}
+		a[binding.action] += binding.scale * in.value(binding.input)
+	}
+	return a
+}
+
+// bindingsFile is read by the window at startup, defaultBindings is used if
+// it does not exist.
+const bindingsFile = "bindings.cfg"
+
+const defaultBindings = `# action input [scale]
+MoveForward W
+MoveForward S -1
+MoveRight D
+MoveRight A -1
+Ascend Space
+Ascend Shift -1
+Yaw ArrowRight
+Yaw ArrowLeft -1
+Pitch ArrowUp
+Pitch ArrowDown -1
+Roll E
+Roll Q -1
+LookX MouseX
+LookY MouseY -1
+Zoom WheelY
+Orbit MouseLeft
+Pan MouseRight
+Fit F
+ToggleOrbit Tab
+ToggleRasterizer R
+ToggleGuardBand G
+CaptureCursor MouseLeft
+ReleaseCursor Escape
+`
+
+// parseBindings reads one binding per line, made of an action name, an input
+// name and an optional scale that defaults to 1. An action may be bound to
+// any number of inputs. Errors are reported as *ParseError.
+func parseBindings(r io.Reader, filename string) (bindings, error) {
+	var b bindings
+
+	err := scanLines(r, filename, func(line int, fields []string) error {
+		if len(fields) < 2 || len(fields) > 3 {
+			return errors.New("binding needs an action, an input and an optional scale")
+		}
+		a, err := parseAction(fields[0])
+		if err != nil {
+			return err
+		}
+		scale := 1.0
+		if len(fields) == 3 {
+			if scale, err = strconv.ParseFloat(fields[2], 64); err != nil {
+				return err
+			}
+		}
+		b = append(b, binding{action: a, input: fields[1], scale: scale, filename: filename, line: line})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+func parseAction(s string) (action, error) {
+	for a, name := range actionNames {
+		if strings.EqualFold(s, name) {
+			return action(a), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown action %q", s)
+}
+
+// loadBindings reads the bindings file filename, or the default bindings if
+// it does not exist.
+func loadBindings(filename string) (bindings, error) {
+	file, err := os.Open(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return parseBindings(strings.NewReader(defaultBindings), "default bindings")
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return parseBindings(file, filename)
+}
@@ -0,0 +1,120 @@
+//go:build !headless
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// Names of the mouse inputs. Keys are named as ebiten.Key's text form, for
+// example W, ArrowUp or Space.
+const (
+	inputMouseX      = "MouseX"
+	inputMouseY      = "MouseY"
+	inputWheelY      = "WheelY"
+	inputMouseLeft   = "MouseLeft"
+	inputMouseRight  = "MouseRight"
+	inputMouseMiddle = "MouseMiddle"
+)
+
+var mouseButtons = map[string]ebiten.MouseButton{
+	inputMouseLeft:   ebiten.MouseButtonLeft,
+	inputMouseRight:  ebiten.MouseButtonRight,
+	inputMouseMiddle: ebiten.MouseButtonMiddle,
+}
+
+// ebitenInput is the inputState of ebiten's keyboard and mouse.
+type ebitenInput struct {
+	keys map[string]ebiten.Key
+
+	// cursor motion and wheel of the current frame
+	dx, dy, wheel float64
+
+	// cursor position of the previous frame, invalid after the cursor mode
+	// changed so capturing the cursor does not turn the camera
+	cursorX, cursorY int
+	cursorValid      bool
+	cursorMode       ebiten.CursorModeType
+}
+
+// newEbitenInput resolves the inputs named by b.
+func newEbitenInput(b bindings) (*ebitenInput, error) {
+	in := &ebitenInput{keys: map[string]ebiten.Key{}}
+	for _, binding := range b {
+		switch binding.input {
+		case inputMouseX, inputMouseY, inputWheelY:
+			continue
+		}
+		if _, ok := mouseButtons[binding.input]; ok {
+			continue
+		}
+
+		var key ebiten.Key
+		if err := key.UnmarshalText([]byte(binding.input)); err != nil {
+			return nil, &ParseError{Filename: binding.filename, Line: binding.line, Err: fmt.Errorf("unknown input %q", binding.input)}
+		}
+		in.keys[binding.input] = key
+	}
+	return in, nil
+}
+
+// update samples the mouse motion, it is called once at the start of every
+// frame.
+func (in *ebitenInput) update() {
+	_, in.wheel = ebiten.Wheel()
+
+	x, y := ebiten.CursorPosition()
+	if mode := ebiten.CursorMode(); mode != in.cursorMode {
+		in.cursorMode = mode
+		in.cursorValid = false
+	}
+	in.dx, in.dy = 0, 0
+	if in.cursorValid {
+		in.dx, in.dy = float64(x-in.cursorX), float64(y-in.cursorY)
+	}
+	in.cursorX, in.cursorY = x, y
+	in.cursorValid = true
+}
+
+func (in *ebitenInput) value(input string) float64 {
+	switch input {
+	case inputMouseX:
+		return in.dx
+	case inputMouseY:
+		return in.dy
+	case inputWheelY:
+		return in.wheel
+	}
+
+	var pressed bool
+	if button, ok := mouseButtons[input]; ok {
+		pressed = ebiten.IsMouseButtonPressed(button)
+	} else {
+		pressed = ebiten.IsKeyPressed(in.keys[input])
+	}
+	if pressed {
+		return 1
+	}
+	return 0
+}
+
+func (in *ebitenInput) justPressed(input string) bool {
+	if button, ok := mouseButtons[input]; ok {
+		return inpututil.IsMouseButtonJustPressed(button)
+	}
+	return inpututil.IsKeyJustPressed(in.keys[input])
+}
+
+// ebitenCursor is the cursor of ebiten's window.
+type ebitenCursor struct{}
+
+func (ebitenCursor) setCaptured(captured bool) {
+	if captured {
+		ebiten.SetCursorMode(ebiten.CursorModeCaptured)
+	} else {
+		ebiten.SetCursorMode(ebiten.CursorModeVisible)
+	}
+}
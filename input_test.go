@@ -0,0 +1,148 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+// fakeInput is an inputState holding the value of every input, and the
+// inputs pressed in this frame.
+type fakeInput struct {
+	values  map[string]float64
+	pressed map[string]bool
+}
+
+func (in *fakeInput) value(input string) float64 {
+	return in.values[input]
+}
+
+func (in *fakeInput) justPressed(input string) bool {
+	return in.pressed[input]
+}
+
+func TestDefaultBindings(t *testing.T) {
+	b, err := parseBindings(strings.NewReader(defaultBindings), "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bound := map[action]bool{}
+	for _, binding := range b {
+		bound[binding.action] = true
+	}
+	for a := action(0); a < numActions; a++ {
+		if !bound[a] {
+			t.Errorf("%s has no default binding", a)
+		}
+	}
+}
+
+func TestParseBindingsErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		line  int
+	}{
+		{"MoveForward", 1},
+		{"MoveForward W 1 2", 1},
+		{"# comment\nJump Space", 2},
+		{"Yaw ArrowLeft\nYaw ArrowRight minus", 2},
+	}
+
+	for _, test := range tests {
+		_, err := parseBindings(strings.NewReader(test.input), "test.cfg")
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) {
+			t.Errorf("%q: got %v, want a *ParseError", test.input, err)
+			continue
+		}
+		if parseErr.Line != test.line {
+			t.Errorf("%q: error on line %d, want %d", test.input, parseErr.Line, test.line)
+		}
+	}
+}
+
+func TestBindingsActions(t *testing.T) {
+	b, err := parseBindings(strings.NewReader(`
+movefORWARD W
+MoveForward S -1
+Yaw ArrowRight 2 # faster
+LookY MouseY -1
+Fit F
+Fit MouseMiddle
+`), "test.cfg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		in   fakeInput
+		want actions
+	}{
+		{"nothing pressed", fakeInput{}, actions{}},
+		{"forward", fakeInput{values: map[string]float64{"W": 1}}, actions{actionMoveForward: 1}},
+		{"opposite keys cancel", fakeInput{values: map[string]float64{"W": 1, "S": 1}}, actions{}},
+		{"scaled", fakeInput{values: map[string]float64{"ArrowRight": 1}}, actions{actionYaw: 2}},
+		{"inverted mouse", fakeInput{values: map[string]float64{"MouseY": 5}}, actions{actionLookY: -5}},
+		{"held trigger", fakeInput{values: map[string]float64{"F": 1}}, actions{}},
+		{"trigger", fakeInput{pressed: map[string]bool{"F": true, "MouseMiddle": true}}, actions{actionFit: 1}},
+	}
+
+	for _, test := range tests {
+		if got := b.actions(&test.in); got != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestCameraUpdate(t *testing.T) {
+	b, err := parseBindings(strings.NewReader(defaultBindings), "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := newCamera(vec3d{0, 0, 0, 1}, 0)
+	in := fakeInput{values: map[string]float64{"W": 1, "D": 1}}
+	a := b.actions(&in)
+	c.update(&a, 0.5)
+	if want := (vec3d{-2, 0, 2, 1}); !vectorsEqual(c.pos, want) {
+		t.Errorf("move: got %v, want %v", c.pos, want)
+	}
+
+	in = fakeInput{values: map[string]float64{"ArrowRight": 1, "MouseY": -100}}
+	a = b.actions(&in)
+	c.update(&a, 0.5)
+	if want := turnSpeed * 0.5; math.Abs(c.yaw-want) > matrixEpsilon {
+		t.Errorf("yaw: got %g, want %g", c.yaw, want)
+	}
+	if want := 100 * mouseSensitivity; math.Abs(c.pitch-want) > matrixEpsilon {
+		t.Errorf("pitch: got %g, want %g", c.pitch, want)
+	}
+}
+
+func TestOrbitCameraUpdate(t *testing.T) {
+	b, err := parseBindings(strings.NewReader(defaultBindings), "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o := orbitCamera{distance: 10}
+	in := fakeInput{values: map[string]float64{"MouseX": 100, "WheelY": 2}}
+	a := b.actions(&in)
+	o.update(&a)
+	if o.yaw != 0 {
+		t.Errorf("cursor motion without a button turned the camera to yaw %g", o.yaw)
+	}
+	if want := 10 / zoomStep / zoomStep; math.Abs(o.distance-want) > matrixEpsilon {
+		t.Errorf("zoom: got distance %g, want %g", o.distance, want)
+	}
+
+	in = fakeInput{values: map[string]float64{"MouseX": 100, "MouseLeft": 1}}
+	a = b.actions(&in)
+	o.update(&a)
+	if want := 100 * mouseSensitivity; math.Abs(o.yaw-want) > matrixEpsilon {
+		t.Errorf("orbit: got yaw %g, want %g", o.yaw, want)
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"io/fs"
+	"math"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Material is a named surface description from an MTL material library.
+type Material struct {
+	Name string
+	// Ka, Kd, Ks and Ke are the ambient, diffuse, specular and emissive
+	// colors as r, g, b in [0, 1].
+	Ka, Kd, Ks, Ke [3]float64
+	// Ns is the specular exponent, Ni the index of refraction and D the
+	// opacity.
+	Ns, Ni, D float64
+	Illum     int
+	// MapKd is the path of the diffuse texture, resolved relative to the
+	// material library.
+	MapKd string
+	// Texture is what the rasterizer samples for faces using this material.
+	// It is the MapKd image if there is one and a single Kd colored texel
+	// otherwise, or when the MapKd file does not exist.
+	Texture TextureAtlas
+}
+
+func newMaterial(name string) *Material {
+	return &Material{
+		Name: name,
+		Kd:   [3]float64{0.8, 0.8, 0.8},
+		D:    1,
+	}
+}
+
+// loadMaterials reads the MTL file filename and loads the textures of its
+// materials, both through textures. Like missing material libraries, missing
+// textures are skipped and their materials fall back to their Kd color.
+func loadMaterials(filename string, textures *TextureLoader) (map[string]*Material, error) {
+	file, err := textures.openFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	materials, err := parseMTL(file, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, material := range materials {
+		material.Texture = newSolidTexture(material.Kd)
+		if material.MapKd == "" {
+			continue
+		}
+
+		tex, err := textures.Load(material.MapKd)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("material %s: %w", material.Name, err)
+		}
+		material.Texture = tex
+	}
+	return materials, nil
+}
+
+// parseMTL reads the materials defined in r. Relative texture paths are
+// resolved against the directory of filename, textures are not loaded.
+func parseMTL(r io.Reader, filename string) (map[string]*Material, error) {
+	materials := map[string]*Material{}
+	var current *Material
+
+	err := scanLines(r, filename, func(_ int, fields []string) error {
+		if fields[0] == "newmtl" {
+			if len(fields) < 2 {
+				return errors.New("newmtl needs a name")
+			}
+			current = newMaterial(strings.Join(fields[1:], " "))
+			materials[current.Name] = current
+			return nil
+		}
+		if current == nil {
+			return fmt.Errorf("%s before newmtl", fields[0])
+		}
+
+		var err error
+		switch fields[0] {
+		case "Ka":
+			current.Ka, err = parseRGB(fields[1:])
+		case "Kd":
+			current.Kd, err = parseRGB(fields[1:])
+		case "Ks":
+			current.Ks, err = parseRGB(fields[1:])
+		case "Ke":
+			current.Ke, err = parseRGB(fields[1:])
+		case "Ns":
+			current.Ns, err = parseScalar(fields[1:])
+		case "Ni":
+			current.Ni, err = parseScalar(fields[1:])
+		case "d":
+			current.D, err = parseScalar(fields[1:])
+		case "Tr":
+			var tr float64
+			tr, err = parseScalar(fields[1:])
+			current.D = 1 - tr
+		case "illum":
+			var illum float64
+			illum, err = parseScalar(fields[1:])
+			current.Illum = int(illum)
+		case "map_Kd":
+			if len(fields) < 2 {
+				return errors.New("map_Kd needs a file name")
+			}
+			current.MapKd = resolvePath(filename, textureName(fields[1:]))
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return materials, nil
+}
+
+// resolvePath returns the path of name, which a file filename refers to.
+// Relative names are resolved against the directory of filename, absolute
+// names are kept as they are.
+func resolvePath(filename, name string) string {
+	if filepath.IsAbs(name) || path.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(filepath.Dir(filename), name)
+}
+
+// textureName extracts the file name from the arguments of a map_ statement.
+// Names may contain spaces, unless options precede them in which case the
+// name is the last argument.
+func textureName(fields []string) string {
+	if strings.HasPrefix(fields[0], "-") {
+		return fields[len(fields)-1]
+	}
+	return strings.Join(fields, " ")
+}
+
+func parseRGB(fields []string) ([3]float64, error) {
+	if len(fields) == 1 {
+		// a single value sets all three channels
+		fields = []string{fields[0], fields[0], fields[0]}
+	}
+	if len(fields) != 3 {
+		return [3]float64{}, fmt.Errorf("expected r g b, got %d values", len(fields))
+	}
+
+	c, err := parseFloats(fields)
+	if err != nil {
+		return [3]float64{}, err
+	}
+	return [3]float64{c[0], c[1], c[2]}, nil
+}
+
+func parseScalar(fields []string) (float64, error) {
+	if len(fields) != 1 {
+		return 0, fmt.Errorf("expected a single value, got %d", len(fields))
+	}
+	c, err := parseFloats(fields)
+	if err != nil {
+		return 0, err
+	}
+	return c[0], nil
+}
+
+// solidTexture is a one texel TextureAtlas for materials without a diffuse
+// map.
+type solidTexture struct {
+	c     color.RGBA
+	pix   []uint8
+	level [1]mipLevel
+}
+
+func newSolidTexture(rgb [3]float64) *solidTexture {
+	channel := func(f float64) uint8 {
+		return uint8(math.Round(math.Max(0, math.Min(1, f)) * 255))
+	}
+	c := color.RGBA{channel(rgb[0]), channel(rgb[1]), channel(rgb[2]), 255}
+	t := &solidTexture{c: c, pix: []uint8{c.R, c.G, c.B, c.A}}
+	t.level[0] = mipLevel{pix: t.pix, w: 1, h: 1}
+	return t
+}
+
+func (t *solidTexture) W() int {
+	return 1
+}
+
+func (t *solidTexture) H() int {
+	return 1
+}
+
+func (t *solidTexture) ColorAt(x, y int) color.Color {
+	return t.c
+}
+
+func (t *solidTexture) Pix() []uint8 {
+	return t.pix
+}
+
+func (t *solidTexture) Filter() TextureFilter {
+	return FilterNearest
+}
+
+func (t *solidTexture) AddressMode() AddressMode {
+	return AddressClampToEdge
+}
+
+func (t *solidTexture) MipmapFilter() MipmapFilter {
+	return MipmapNone
+}
+
+func (t *solidTexture) MipLevels() int {
+	return 1
+}
+
+func (t *solidTexture) mipLevels() []mipLevel {
+	return t.level[:]
+}
+
+func (t *solidTexture) MipLevel(level int) ([]uint8, int, int) {
+	return t.pix, 1, 1
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParseMTL(t *testing.T) {
+	src := `# comment
+newmtl Colors
+Ns 250.000000
+Ka 1.000000 1.000000 1.000000
+Ks 0.500000 0.500000 0.500000
+Ke 0.000000 0.000000 0.000000
+Ni 1.500000
+d 1.000000
+illum 2
+map_Kd colors.png
+
+newmtl Material
+Kd 0.1 0.2 0.3
+Tr 0.25
+map_Kd -s 2 2 1 wall.png
+
+newmtl Absolute
+map_Kd /srv/tex/a.png
+`
+	materials, err := parseMTL(strings.NewReader(src), filepath.Join("models", "test.mtl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(materials) != 3 {
+		t.Fatalf("got %d materials, want 3", len(materials))
+	}
+
+	colors := materials["Colors"]
+	if colors.Ns != 250 || colors.Ni != 1.5 || colors.D != 1 || colors.Illum != 2 {
+		t.Errorf("got scalars Ns=%v Ni=%v d=%v illum=%v", colors.Ns, colors.Ni, colors.D, colors.Illum)
+	}
+	if colors.Ks != [3]float64{0.5, 0.5, 0.5} {
+		t.Errorf("got Ks %v", colors.Ks)
+	}
+	if colors.Kd != [3]float64{0.8, 0.8, 0.8} {
+		t.Errorf("got default Kd %v", colors.Kd)
+	}
+	if want := filepath.Join("models", "colors.png"); colors.MapKd != want {
+		t.Errorf("got map_Kd %q, want %q", colors.MapKd, want)
+	}
+
+	material := materials["Material"]
+	if material.Kd != [3]float64{0.1, 0.2, 0.3} {
+		t.Errorf("got Kd %v", material.Kd)
+	}
+	if material.D != 0.75 {
+		t.Errorf("got d %v, want 0.75", material.D)
+	}
+	if want := filepath.Join("models", "wall.png"); material.MapKd != want {
+		t.Errorf("got map_Kd %q, want %q", material.MapKd, want)
+	}
+
+	// absolute paths do not depend on where the library is
+	if got, want := materials["Absolute"].MapKd, "/srv/tex/a.png"; got != want {
+		t.Errorf("got map_Kd %q, want %q", got, want)
+	}
+}
+
+func TestParseMTLErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		line int
+	}{
+		{"statement before newmtl", "Kd 1 1 1\n", 1},
+		{"bad color", "newmtl A\nKd 1 x 1\n", 2},
+		{"short color", "newmtl A\n\nKs 1 1\n", 3},
+		{"missing name", "newmtl\n", 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := parseMTL(strings.NewReader(test.src), "test.mtl")
+
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("got %v, want a *ParseError", err)
+			}
+			if parseErr.Line != test.line {
+				t.Errorf("got line %d, want %d", parseErr.Line, test.line)
+			}
+		})
+	}
+}
+
+func TestParseOBJMaterials(t *testing.T) {
+	red := newMaterial("Red")
+	green := newMaterial("Green")
+	loadLibrary := func(name string) (map[string]*Material, error) {
+		switch name {
+		case "colors lib.mtl":
+			return map[string]*Material{"Red": red, "Green": green}, nil
+		default:
+			return nil, fs.ErrNotExist
+		}
+	}
+
+	src := `mtllib colors lib.mtl
+mtllib missing.mtl
+v 0 0 0
+v 1 0 0
+v 0 1 0
+f 1 2 3
+usemtl Red
+f 1 2 3
+usemtl Green
+f 1 2 3
+usemtl Unknown
+f 1 2 3
+`
+	m, err := parseOBJ(strings.NewReader(src), "test.obj", loadLibrary)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []*Material{nil, red, green, nil}
+	for i, mat := range m.mats {
+		if mat != want[i] {
+			t.Errorf("triangle %d: got material %v, want %v", i, mat, want[i])
+		}
+	}
+}
+
+func TestLoadMaterialTextures(t *testing.T) {
+	materials, err := loadMaterials("cube4.mtl", NewTextureLoader(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tex := materials["Colors"].Texture; tex.W() <= 1 {
+		t.Errorf("Colors: map_Kd texture was not loaded")
+	}
+	if c := materials["Material"].Texture.ColorAt(0, 0); c != newSolidTexture([3]float64{0.8, 0.8, 0.8}).c {
+		t.Errorf("Material: got %v, want a solid Kd texture", c)
+	}
+}
+
+func TestLoadMaterialMissingTexture(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "missing.mtl")
+	mtl := "newmtl Red\nKd 1 0 0\nmap_Kd missing.png\n"
+	if err := os.WriteFile(filename, []byte(mtl), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	materials, err := loadMaterials(filename, NewTextureLoader(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, want := materials["Red"].Texture.ColorAt(0, 0), newSolidTexture([3]float64{1, 0, 0}).c; c != want {
+		t.Errorf("got %v, want the Kd color %v", c, want)
+	}
+}
+
+func TestLoadFromFS(t *testing.T) {
+	fsys := encodedImages(t)
+	fsys["models/quad.obj"] = &fstest.MapFile{Data: []byte("mtllib quad.mtl\nv 0 0 0\nv 1 0 0\nv 1 1 0\nusemtl Tex\nf 1 2 3\n")}
+	fsys["models/quad.mtl"] = &fstest.MapFile{Data: []byte("newmtl Tex\nmap_Kd ../textures/a.png\n")}
+
+	m := mesh{}
+	if err := m.Load("models/quad.obj", NewTextureLoader(fsys)); err != nil {
+		t.Fatal(err)
+	}
+	if mat := m.mats[0]; mat == nil || mat.Texture.W() != 4 {
+		t.Errorf("got material %v, want Tex with the 4x2 texture", mat)
+	}
+}
+
+func TestLoadResolvesMaterials(t *testing.T) {
+	m := mesh{}
+	if err := m.Load("cube3.obj", NewTextureLoader(nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, mat := range m.mats {
+		if mat == nil || mat.Name != "Material" {
+			t.Fatalf("got material %v, want Material", mat)
+		}
+	}
+	if want := "colors.png"; m.mats[0].MapKd != want {
+		t.Errorf("got map_Kd %q, want %q", m.mats[0].MapKd, want)
+	}
+}
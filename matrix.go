@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"math"
 )
 
@@ -49,41 +50,85 @@ func matrixMakeIdentity() mat4x4 {
 	return m
 }
 
-func (m *mat4x4) translate(x, y, z float64) {
-	m.m[0][0] = 1
-	m.m[1][1] = 1
-	m.m[2][2] = 1
-	m.m[3][3] = 1
+func matrixMakeTranslation(x, y, z float64) mat4x4 {
+	m := matrixMakeIdentity()
 	m.m[3][0] = x
 	m.m[3][1] = y
 	m.m[3][2] = z
+	return m
+}
+
+func matrixMakeScale(x, y, z float64) mat4x4 {
+	m := mat4x4{}
+	m.m[0][0] = x
+	m.m[1][1] = y
+	m.m[2][2] = z
+	m.m[3][3] = 1
+	return m
 }
 
-func (m *mat4x4) rotateY(angleRad float64) {
+// matrixMakeRotationX turns y towards z.
+func matrixMakeRotationX(angleRad float64) mat4x4 {
+	m := matrixMakeIdentity()
+	m.m[1][1] = math.Cos(angleRad)
+	m.m[1][2] = math.Sin(angleRad)
+	m.m[2][1] = -math.Sin(angleRad)
+	m.m[2][2] = math.Cos(angleRad)
+	return m
+}
+
+// matrixMakeRotationY turns z towards -x, the opposite sense of the X and Z
+// rotations, which the camera's yaw is built on.
+func matrixMakeRotationY(angleRad float64) mat4x4 {
+	m := matrixMakeIdentity()
 	m.m[0][0] = math.Cos(angleRad)
 	m.m[0][2] = math.Sin(angleRad)
 	m.m[2][0] = -math.Sin(angleRad)
-	m.m[1][1] = 1
 	m.m[2][2] = math.Cos(angleRad)
-	m.m[3][3] = 1
+	return m
 }
 
-func (m *mat4x4) rotateZ(angleRad float64) {
+// matrixMakeRotationZ turns x towards y.
+func matrixMakeRotationZ(angleRad float64) mat4x4 {
+	m := matrixMakeIdentity()
 	m.m[0][0] = math.Cos(angleRad)
 	m.m[0][1] = math.Sin(angleRad)
 	m.m[1][0] = -math.Sin(angleRad)
 	m.m[1][1] = math.Cos(angleRad)
-	m.m[2][2] = 1
-	m.m[3][3] = 1
+	return m
 }
 
-func (m *mat4x4) rotateX(angleRad float64) {
-	m.m[0][0] = 1
-	m.m[1][1] = math.Cos(angleRad)
-	m.m[1][2] = math.Sin(angleRad)
-	m.m[2][1] = -math.Sin(angleRad)
-	m.m[2][2] = math.Cos(angleRad)
-	m.m[3][3] = 1
+// matrixMakeRotationAxis rotates by angleRad around axis, which need not be
+// normalized. Around the x and z axis it matches matrixMakeRotationX and
+// matrixMakeRotationZ, around the y axis it matches matrixMakeRotationY with
+// the angle negated.
+func matrixMakeRotationAxis(axis vec3d, angleRad float64) mat4x4 {
+	axis.Normalize()
+	x, y, z := axis.x, axis.y, axis.z
+	c := math.Cos(angleRad)
+	s := math.Sin(angleRad)
+	t := 1 - c
+
+	m := matrixMakeIdentity()
+	m.m[0][0] = c + t*x*x
+	m.m[0][1] = t*x*y + s*z
+	m.m[0][2] = t*x*z - s*y
+	m.m[1][0] = t*x*y - s*z
+	m.m[1][1] = c + t*y*y
+	m.m[1][2] = t*y*z + s*x
+	m.m[2][0] = t*x*z + s*y
+	m.m[2][1] = t*y*z - s*x
+	m.m[2][2] = c + t*z*z
+	return m
+}
+
+// matrixMakeTRS composes a transform that scales first, then rotates and
+// then translates. rotation should only hold a rotation.
+func matrixMakeTRS(translation vec3d, rotation *mat4x4, scale vec3d) mat4x4 {
+	m := matrixMakeScale(scale.x, scale.y, scale.z)
+	m = m.multiplyMatrix(rotation)
+	t := matrixMakeTranslation(translation.x, translation.y, translation.z)
+	return m.multiplyMatrix(&t)
 }
 
 func matrixMakeProjection(fFovRad, fAspectRatio, fNear, fFar float64) mat4x4 {
@@ -97,7 +142,10 @@ func matrixMakeProjection(fFovRad, fAspectRatio, fNear, fFar float64) mat4x4 {
 	return matrix
 }
 
-func (m *mat4x4) pointAt(pos, target, up *vec3d) {
+// matrixPointAt places an object at pos facing target, with up as the
+// rough up direction.
+func matrixPointAt(pos, target, up *vec3d) mat4x4 {
+	m := mat4x4{}
 	newForward := target.Sub(pos)
 	newForward.Normalize()
 
@@ -122,8 +170,12 @@ func (m *mat4x4) pointAt(pos, target, up *vec3d) {
 	m.m[3][1] = pos.y
 	m.m[3][2] = pos.z
 	m.m[3][3] = 1.0
+	return m
 }
 
+// matrixQuickInverse inverts m by transposing its rotation and rotating the
+// negated translation, which is only valid for rigid transforms like the one
+// of matrixPointAt. Use inverse for anything that scales or shears.
 func matrixQuickInverse(m *mat4x4) mat4x4 {
 	matrix := mat4x4{}
 	matrix.m[0][0] = m.m[0][0]
@@ -144,3 +196,133 @@ func matrixQuickInverse(m *mat4x4) mat4x4 {
 	matrix.m[3][3] = 1.0
 	return matrix
 }
+
+func matrixMakeView(pos, lookDirection *vec3d) mat4x4 {
+	up := vec3d{0, 1, 0, 0}
+	target := pos.Add(lookDirection)
+
+	camera := matrixPointAt(pos, &target, &up)
+	return matrixQuickInverse(&camera)
+}
+
+func lookDirection(fYaw float64) vec3d {
+	target := vec3d{0, 0, 1, 0}
+
+	matCameraRot := matrixMakeRotationY(fYaw)
+
+	return matCameraRot.matrixMultiplyVector(&target)
+}
+
+// errSingularMatrix is returned when inverting a matrix without an inverse.
+var errSingularMatrix = errors.New("matrix is singular")
+
+// singularEpsilon is the determinant, relative to the largest one a matrix
+// with the same row lengths can have, below which it is treated as singular.
+const singularEpsilon = 1e-12
+
+func (m *mat4x4) transpose() mat4x4 {
+	matrix := mat4x4{}
+	for r := 0; r < 4; r++ {
+		for c := 0; c < 4; c++ {
+			matrix.m[c][r] = m.m[r][c]
+		}
+	}
+	return matrix
+}
+
+func (m *mat4x4) determinant() float64 {
+	_, det := m.adjugate()
+	return det
+}
+
+// singular reports whether det, the determinant of m, is too close to zero
+// for m to have an inverse. It is compared to the product of m's row lengths,
+// which bounds the determinant and is reached when the rows are orthogonal,
+// so scaling m does not change the outcome.
+func (m *mat4x4) singular(det float64) bool {
+	bound := 1.0
+	for r := 0; r < 4; r++ {
+		row := &m.m[r]
+		bound *= math.Sqrt(row[0]*row[0] + row[1]*row[1] + row[2]*row[2] + row[3]*row[3])
+	}
+	return !(math.Abs(det) > singularEpsilon*bound)
+}
+
+// inverse returns the inverse of m, or errSingularMatrix if its determinant
+// is too close to zero for one to exist.
+func (m *mat4x4) inverse() (mat4x4, error) {
+	adj, det := m.adjugate()
+	if m.singular(det) {
+		return mat4x4{}, errSingularMatrix
+	}
+	for r := 0; r < 4; r++ {
+		for c := 0; c < 4; c++ {
+			adj.m[r][c] /= det
+		}
+	}
+	return adj, nil
+}
+
+// normalMatrix returns the matrix that transforms normals along with points
+// transformed by m: the inverse transpose of its upper 3x3 part, which keeps
+// normals perpendicular to surfaces under non-uniform scale.
+func (m *mat4x4) normalMatrix() (mat4x4, error) {
+	linear := *m
+	for i := 0; i < 3; i++ {
+		linear.m[3][i] = 0
+		linear.m[i][3] = 0
+	}
+	linear.m[3][3] = 1
+
+	inv, err := linear.inverse()
+	if err != nil {
+		return mat4x4{}, err
+	}
+	return inv.transpose(), nil
+}
+
+// adjugate returns the adjugate of m, the transposed matrix of its
+// cofactors, and its determinant. Both are built from the 2x2 minors of the
+// top and bottom two rows.
+func (m *mat4x4) adjugate() (mat4x4, float64) {
+	a := &m.m
+
+	s0 := a[0][0]*a[1][1] - a[1][0]*a[0][1]
+	s1 := a[0][0]*a[1][2] - a[1][0]*a[0][2]
+	s2 := a[0][0]*a[1][3] - a[1][0]*a[0][3]
+	s3 := a[0][1]*a[1][2] - a[1][1]*a[0][2]
+	s4 := a[0][1]*a[1][3] - a[1][1]*a[0][3]
+	s5 := a[0][2]*a[1][3] - a[1][2]*a[0][3]
+
+	c5 := a[2][2]*a[3][3] - a[3][2]*a[2][3]
+	c4 := a[2][1]*a[3][3] - a[3][1]*a[2][3]
+	c3 := a[2][1]*a[3][2] - a[3][1]*a[2][2]
+	c2 := a[2][0]*a[3][3] - a[3][0]*a[2][3]
+	c1 := a[2][0]*a[3][2] - a[3][0]*a[2][2]
+	c0 := a[2][0]*a[3][1] - a[3][0]*a[2][1]
+
+	det := s0*c5 - s1*c4 + s2*c3 + s3*c2 - s4*c1 + s5*c0
+
+	adj := mat4x4{}
+	adj.m[0][0] = a[1][1]*c5 - a[1][2]*c4 + a[1][3]*c3
+	adj.m[0][1] = -a[0][1]*c5 + a[0][2]*c4 - a[0][3]*c3
+	adj.m[0][2] = a[3][1]*s5 - a[3][2]*s4 + a[3][3]*s3
+	adj.m[0][3] = -a[2][1]*s5 + a[2][2]*s4 - a[2][3]*s3
+
+	adj.m[1][0] = -a[1][0]*c5 + a[1][2]*c2 - a[1][3]*c1
+	adj.m[1][1] = a[0][0]*c5 - a[0][2]*c2 + a[0][3]*c1
+	adj.m[1][2] = -a[3][0]*s5 + a[3][2]*s2 - a[3][3]*s1
+	adj.m[1][3] = a[2][0]*s5 - a[2][2]*s2 + a[2][3]*s1
+
+	adj.m[2][0] = a[1][0]*c4 - a[1][1]*c2 + a[1][3]*c0
+	adj.m[2][1] = -a[0][0]*c4 + a[0][1]*c2 - a[0][3]*c0
+	adj.m[2][2] = a[3][0]*s4 - a[3][1]*s2 + a[3][3]*s0
+	adj.m[2][3] = -a[2][0]*s4 + a[2][1]*s2 - a[2][3]*s0
+
+	adj.m[3][0] = -a[1][0]*c3 + a[1][1]*c1 - a[1][2]*c0
+	adj.m[3][1] = a[0][0]*c3 - a[0][1]*c1 + a[0][2]*c0
+	adj.m[3][2] = -a[3][0]*s3 + a[3][1]*s1 - a[3][2]*s0
+	adj.m[3][3] = a[2][0]*s3 - a[2][1]*s1 + a[2][2]*s0
+
+	return adj, det
+}
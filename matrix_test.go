@@ -0,0 +1,248 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+const matrixEpsilon = 1e-9
+
+func matricesEqual(a, b *mat4x4) bool {
+	for r := 0; r < 4; r++ {
+		for c := 0; c < 4; c++ {
+			if math.Abs(a.m[r][c]-b.m[r][c]) > matrixEpsilon {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func vectorsEqual(a, b vec3d) bool {
+	return math.Abs(a.x-b.x) < matrixEpsilon && math.Abs(a.y-b.y) < matrixEpsilon &&
+		math.Abs(a.z-b.z) < matrixEpsilon && math.Abs(a.w-b.w) < matrixEpsilon
+}
+
+func TestMatrixTransforms(t *testing.T) {
+	rotation := matrixMakeRotationAxis(vec3d{0, 0, 1, 0}, math.Pi/2)
+	tests := []struct {
+		name string
+		m    mat4x4
+		in   vec3d
+		want vec3d
+	}{
+		{"identity", matrixMakeIdentity(), vec3d{1, 2, 3, 1}, vec3d{1, 2, 3, 1}},
+		{"translation", matrixMakeTranslation(1, 2, 3), vec3d{1, 1, 1, 1}, vec3d{2, 3, 4, 1}},
+		{"translation leaves directions", matrixMakeTranslation(1, 2, 3), vec3d{1, 1, 1, 0}, vec3d{1, 1, 1, 0}},
+		{"scale", matrixMakeScale(2, 3, 4), vec3d{1, 1, 1, 1}, vec3d{2, 3, 4, 1}},
+		{"rotation x", matrixMakeRotationX(math.Pi / 2), vec3d{0, 1, 0, 1}, vec3d{0, 0, 1, 1}},
+		{"rotation y", matrixMakeRotationY(math.Pi / 2), vec3d{0, 0, 1, 1}, vec3d{-1, 0, 0, 1}},
+		{"rotation z", matrixMakeRotationZ(math.Pi / 2), vec3d{1, 0, 0, 1}, vec3d{0, 1, 0, 1}},
+		{"rotation axis", matrixMakeRotationAxis(vec3d{1, 1, 1, 0}, 2*math.Pi/3), vec3d{1, 0, 0, 1}, vec3d{0, 1, 0, 1}},
+		{"trs", matrixMakeTRS(vec3d{10, 0, 0, 1}, &rotation, vec3d{2, 2, 2, 1}), vec3d{1, 0, 0, 1}, vec3d{10, 2, 0, 1}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.m.matrixMultiplyVector(&test.in); !vectorsEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMatrixRotationAxisMatchesAxes(t *testing.T) {
+	for _, angle := range []float64{-2, 0.3, 1, math.Pi} {
+		tests := []struct {
+			axis vec3d
+			want mat4x4
+		}{
+			{vec3d{1, 0, 0, 0}, matrixMakeRotationX(angle)},
+			{vec3d{0, 2, 0, 0}, matrixMakeRotationY(-angle)},
+			{vec3d{0, 0, 1, 0}, matrixMakeRotationZ(angle)},
+		}
+		for _, test := range tests {
+			if got := matrixMakeRotationAxis(test.axis, angle); !matricesEqual(&got, &test.want) {
+				t.Errorf("axis %v, angle %g: got %v, want %v", test.axis, angle, got, test.want)
+			}
+		}
+	}
+}
+
+func TestMatrixDeterminant(t *testing.T) {
+	rotation := matrixMakeRotationAxis(vec3d{1, 2, 3, 0}, 0.7)
+	singular := matrixMakeScale(1, 0, 1)
+	general := mat4x4{m: [4][4]float64{
+		{2, 0, 1, 3},
+		{1, 1, 0, 2},
+		{0, 3, 1, 1},
+		{1, 0, 2, 1},
+	}}
+	tests := []struct {
+		name string
+		m    mat4x4
+		want float64
+	}{
+		{"identity", matrixMakeIdentity(), 1},
+		{"scale", matrixMakeScale(2, 3, 4), 24},
+		{"rotation", rotation, 1},
+		{"translation", matrixMakeTranslation(5, 6, 7), 1},
+		{"singular", singular, 0},
+		{"general", general, -1},
+		{"projection", matrixMakeProjection(1, 1, 0.1, 1000), 1000 * 0.1 / (1000 - 0.1)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.m.determinant(); math.Abs(got-test.want) > matrixEpsilon {
+				t.Errorf("got %g, want %g", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMatrixInverse(t *testing.T) {
+	rotation := matrixMakeRotationAxis(vec3d{1, 2, 3, 0}, 0.7)
+	vCamera := vec3d{1, 2, 3, 1}
+	vLookDirection := lookDirection(0.4)
+	tests := []struct {
+		name string
+		m    mat4x4
+	}{
+		{"identity", matrixMakeIdentity()},
+		{"scale", matrixMakeScale(2, 3, 4)},
+		{"small uniform scale", matrixMakeScale(1e-5, 1e-5, 1e-5)},
+		{"trs", matrixMakeTRS(vec3d{1, -2, 3, 1}, &rotation, vec3d{0.5, 2, 3, 1})},
+		{"view", matrixMakeView(&vCamera, &vLookDirection)},
+		{"projection", matrixMakeProjection(1, 1, 0.1, 1000)},
+		{"general", mat4x4{m: [4][4]float64{
+			{2, 0, 1, 3},
+			{1, 1, 0, 2},
+			{0, 3, 1, 1},
+			{1, 0, 2, 1},
+		}}},
+	}
+
+	identity := matrixMakeIdentity()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			inv, err := test.m.inverse()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := test.m.multiplyMatrix(&inv); !matricesEqual(&got, &identity) {
+				t.Errorf("m * inverse = %v, want identity", got)
+			}
+			if got := inv.multiplyMatrix(&test.m); !matricesEqual(&got, &identity) {
+				t.Errorf("inverse * m = %v, want identity", got)
+			}
+		})
+	}
+}
+
+func TestMatrixInverseSingular(t *testing.T) {
+	tests := []struct {
+		name string
+		m    mat4x4
+	}{
+		{"zero", mat4x4{}},
+		{"flattening scale", matrixMakeScale(1, 1, 0)},
+		{"repeated rows", mat4x4{m: [4][4]float64{
+			{1, 2, 3, 4},
+			{1, 2, 3, 4},
+			{0, 1, 0, 0},
+			{0, 0, 0, 1},
+		}}},
+		{"nearly repeated rows", mat4x4{m: [4][4]float64{
+			{1e3, 2e3, 3e3, 4e3},
+			{1e3, 2e3, 3e3, 4e3 + 1e-12},
+			{0, 1e3, 0, 0},
+			{0, 0, 0, 1e3},
+		}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := test.m.inverse(); !errors.Is(err, errSingularMatrix) {
+				t.Errorf("got %v, want %v", err, errSingularMatrix)
+			}
+		})
+	}
+}
+
+func TestMatrixQuickInverseMatchesInverse(t *testing.T) {
+	pos := vec3d{1, 2, 3, 1}
+	target := vec3d{-4, 0, 7, 1}
+	up := vec3d{0, 1, 0, 0}
+	m := matrixPointAt(&pos, &target, &up)
+
+	quick := matrixQuickInverse(&m)
+	inv, err := m.inverse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !matricesEqual(&quick, &inv) {
+		t.Errorf("got %v, want %v", quick, inv)
+	}
+}
+
+func TestMatrixTranspose(t *testing.T) {
+	m := mat4x4{m: [4][4]float64{
+		{1, 2, 3, 4},
+		{5, 6, 7, 8},
+		{9, 10, 11, 12},
+		{13, 14, 15, 16},
+	}}
+	want := mat4x4{m: [4][4]float64{
+		{1, 5, 9, 13},
+		{2, 6, 10, 14},
+		{3, 7, 11, 15},
+		{4, 8, 12, 16},
+	}}
+	if got := m.transpose(); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestMatrixNormalMatrix(t *testing.T) {
+	rotation := matrixMakeRotationAxis(vec3d{0, 1, 1, 0}, 0.9)
+	tests := []struct {
+		name string
+		m    mat4x4
+	}{
+		{"rotation", rotation},
+		{"uniform scale", matrixMakeTRS(vec3d{1, 2, 3, 1}, &rotation, vec3d{2, 2, 2, 1})},
+		{"non-uniform scale", matrixMakeTRS(vec3d{1, 2, 3, 1}, &rotation, vec3d{1, 4, 0.5, 1})},
+	}
+
+	// a plane through the origin with two tangents and their normal
+	tangent1 := vec3d{1, 1, 0, 0}
+	tangent2 := vec3d{0, 1, 1, 0}
+	normal := tangent1.CrossProduct(&tangent2)
+	normal.w = 0
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			matNormal, err := test.m.normalMatrix()
+			if err != nil {
+				t.Fatal(err)
+			}
+			n := matNormal.matrixMultiplyVector(&normal)
+			if n.w != 0 {
+				t.Errorf("normal got w = %g, want 0", n.w)
+			}
+			for _, tangent := range []vec3d{tangent1, tangent2} {
+				transformed := test.m.matrixMultiplyVector(&tangent)
+				if dp := n.DotProduct(&transformed); math.Abs(dp) > matrixEpsilon {
+					t.Errorf("normal %v is not perpendicular to tangent %v, dot product %g", n, transformed, dp)
+				}
+			}
+		})
+	}
+
+	flat := matrixMakeScale(1, 0, 1)
+	if _, err := flat.normalMatrix(); !errors.Is(err, errSingularMatrix) {
+		t.Errorf("flattening scale: got %v, want %v", err, errSingularMatrix)
+	}
+}
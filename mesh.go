@@ -0,0 +1,195 @@
+package main
+
+type triangle struct {
+	p [3]vec3d
+	t UVs
+	// v holds the remaining attributes of every corner
+	v   [3]varyings
+	mat *Material
+	r   uint32
+	g   uint32
+	b   uint32
+	a   uint32
+}
+
+// Offsets of the attributes in varyings. Adding an attribute, for example a
+// second set of texture coordinates, only takes a new offset here, the
+// clipper interpolates all of them alike.
+const (
+	// varyingNormal is the vertex normal in world space, zero if the model
+	// has none
+	varyingNormal = 0
+	// varyingColor is the vertex color as RGBA in [0, 1]
+	varyingColor = 3
+	// varyingWorld is the vertex position in world space
+	varyingWorld = 7
+	numVaryings  = 10
+)
+
+// varyings are the per-vertex attributes besides position and texture
+// coordinate that are interpolated across a triangle.
+type varyings [numVaryings]float64
+
+func (v *varyings) vec3(offset int) vec3d {
+	return vec3d{v[offset], v[offset+1], v[offset+2], 0}
+}
+
+func (v *varyings) setVec3(offset int, p vec3d) {
+	v[offset], v[offset+1], v[offset+2] = p.x, p.y, p.z
+}
+
+func (v *varyings) color() [4]float64 {
+	return [4]float64(v[varyingColor : varyingColor+4])
+}
+
+func (v *varyings) setColor(c [4]float64) {
+	copy(v[varyingColor:varyingColor+4], c[:])
+}
+
+func (t *triangle) X(index int) float32 {
+	return float32(t.p[index].x)
+}
+
+func (t *triangle) Y(index int) float32 {
+	return float32(t.p[index].y)
+}
+
+func (t *triangle) RGBA() (r, g, b, a uint32) {
+	return t.r, t.g, t.b, t.a
+}
+
+func (t *triangle) Scale() {
+	t.p[0].ScaleW()
+	t.p[1].ScaleW()
+	t.p[2].ScaleW()
+}
+
+// vertex is one entry of a mesh's vertex buffer. Faces that share a
+// corner with the same position, texture coordinate and normal share the
+// vertex, so it is transformed only once per frame.
+type vertex struct {
+	p vec3d
+	t vec2d
+	// n is the normal read from the model, zero if it has none
+	n vec3d
+	// c is the vertex color as RGBA in [0, 1], white if the model has none
+	c [4]float64
+}
+
+// white is the color of vertices without one.
+var white = [4]float64{1, 1, 1, 1}
+
+// mesh is an indexed triangle list. Every triangle is three entries of
+// indices into vertices.
+type mesh struct {
+	vertices []vertex
+	indices  []int32
+	// mats holds the material of every triangle, nil if it has none
+	mats []*Material
+	// tex textures the faces that have no material of their own
+	tex TextureAtlas
+}
+
+// triangles returns the number of triangles in the mesh.
+func (m *mesh) triangles() int {
+	return len(m.indices) / 3
+}
+
+// needsTexture reports whether some triangle has no material and is
+// textured by tex.
+func (m *mesh) needsTexture() bool {
+	for _, mat := range m.mats {
+		if mat == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// triangle assembles the i-th triangle of the mesh from its vertices, in
+// model space.
+func (m *mesh) triangle(i int) triangle {
+	t := triangle{mat: m.mats[i]}
+	for n := 0; n < 3; n++ {
+		v := &m.vertices[m.indices[3*i+n]]
+		t.p[n] = v.p
+		t.t[n] = v.t
+		t.v[n].setVec3(varyingNormal, v.n)
+		t.v[n].setColor(v.c)
+		t.v[n].setVec3(varyingWorld, v.p)
+	}
+	return t
+}
+
+// boundingSphere returns a sphere in model space that encloses all vertices
+// of the mesh. It is centered on their bounding box, which is not the
+// smallest sphere but close enough to frame the model.
+func (m *mesh) boundingSphere() (center vec3d, radius float64) {
+	if len(m.vertices) == 0 {
+		return vec3d{0, 0, 0, 1}, 0
+	}
+
+	lo, hi := m.vertices[0].p, m.vertices[0].p
+	for _, v := range m.vertices[1:] {
+		lo.x, hi.x = min(lo.x, v.p.x), max(hi.x, v.p.x)
+		lo.y, hi.y = min(lo.y, v.p.y), max(hi.y, v.p.y)
+		lo.z, hi.z = min(lo.z, v.p.z), max(hi.z, v.p.z)
+	}
+	center = vec3d{(lo.x + hi.x) / 2, (lo.y + hi.y) / 2, (lo.z + hi.z) / 2, 1}
+
+	for _, v := range m.vertices {
+		d := v.p.Sub(&center)
+		radius = max(radius, d.Length())
+	}
+	return center, radius
+}
+
+func (m *mesh) translateX(dx float64) {
+	for i := range m.vertices {
+		m.vertices[i].p.x += dx
+	}
+}
+
+func (m *mesh) translateZ(dx float64) {
+	for i := range m.vertices {
+		m.vertices[i].p.z += dx
+	}
+}
+
+func (m *mesh) LoadCube() {
+	m.vertices = []vertex{
+		{p: vec3d{0.0, 0.0, 0.0, 1}, t: vec2d{0, 1, 1}, c: white},
+		{p: vec3d{0.0, 1.0, 0.0, 1}, t: vec2d{0, 0, 1}, c: white},
+		{p: vec3d{1.0, 1.0, 0.0, 1}, t: vec2d{1, 0, 1}, c: white},
+		{p: vec3d{1.0, 0.0, 0.0, 1}, t: vec2d{1, 1, 1}, c: white},
+	}
+	m.indices = []int32{0, 1, 2, 0, 2, 3}
+	m.mats = make([]*Material, 2)
+}
+
+// Load replaces the mesh with the faces of the Wavefront OBJ file filename.
+// Material libraries and their textures are resolved relative to the file.
+// The file, its libraries and textures are read through textures, which may
+// be nil to read them from the operating system's file system.
+// Errors in the file are reported as *ParseError.
+func (m *mesh) Load(filename string, textures *TextureLoader) error {
+	if textures == nil {
+		textures = NewTextureLoader(nil)
+	}
+	file, err := textures.openFile(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	loadLibrary := func(name string) (map[string]*Material, error) {
+		return loadMaterials(resolvePath(filename, name), textures)
+	}
+
+	loaded, err := parseOBJ(file, filename, loadLibrary)
+	if err != nil {
+		return err
+	}
+	m.vertices, m.indices, m.mats = loaded.vertices, loaded.indices, loaded.mats
+	return nil
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+var (
+	errIndexOutOfRange = errors.New("index out of range")
+	errMixedFormat     = errors.New("face mixes vertex formats")
+)
+
+// faceVertex is one v, v/vt, v//vn or v/vt/vn reference of a face, resolved
+// to 0-based indices. Missing components are -1.
+type faceVertex struct {
+	v, vt, vn int
+}
+
+// parseOBJ reads vertices, texture coordinates, normals and faces from r
+// into an indexed mesh. Face corners referencing the same position, texture
+// coordinate and normal share one vertex of the mesh. Vertex colors may
+// follow the position as the common "v x y z r g b" extension. The vertex
+// format is detected per face, faces with more than three vertices are
+// triangulated as a fan around their first vertex. Statements the renderer
+// has no use for are skipped.
+//
+// Material libraries named by mtllib are read through loadLibrary, which may
+// be nil to ignore materials. Libraries that do not exist are skipped and
+// faces using their materials are left without one.
+func parseOBJ(r io.Reader, filename string, loadLibrary func(name string) (map[string]*Material, error)) (*mesh, error) {
+	var positions []vec3d
+	var colors [][4]float64
+	var texs []vec2d
+	var normals []vec3d
+	m := &mesh{}
+	shared := map[faceVertex]int32{}
+
+	materials := map[string]*Material{}
+	var material *Material
+
+	err := scanLines(r, filename, func(_ int, fields []string) error {
+		switch fields[0] {
+		case "v":
+			if len(fields) < 4 {
+				return fmt.Errorf("vertex needs 3 coordinates, got %d", len(fields)-1)
+			}
+			c, err := parseFloats(fields[1:min(len(fields), 7)])
+			if err != nil {
+				return err
+			}
+			positions = append(positions, vec3d{c[0], c[1], c[2], 1})
+			color := white
+			if len(c) == 6 {
+				color = [4]float64{c[3], c[4], c[5], 1}
+			}
+			colors = append(colors, color)
+		case "vt":
+			if len(fields) < 2 {
+				return errors.New("texture coordinate needs at least 1 component")
+			}
+			c, err := parseFloats(fields[1:min(len(fields), 3)])
+			if err != nil {
+				return err
+			}
+			v := vec2d{u: c[0], w: 1}
+			if len(c) > 1 {
+				v.v = c[1]
+			}
+			texs = append(texs, v)
+		case "vn":
+			if len(fields) < 4 {
+				return fmt.Errorf("normal needs 3 components, got %d", len(fields)-1)
+			}
+			c, err := parseFloats(fields[1:4])
+			if err != nil {
+				return err
+			}
+			normals = append(normals, vec3d{c[0], c[1], c[2], 0})
+		case "mtllib":
+			if len(fields) < 2 {
+				return errors.New("mtllib needs a file name")
+			}
+			if loadLibrary == nil {
+				return nil
+			}
+			library, err := loadLibrary(strings.Join(fields[1:], " "))
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			for name, mat := range library {
+				materials[name] = mat
+			}
+		case "usemtl":
+			if len(fields) < 2 {
+				return errors.New("usemtl needs a material name")
+			}
+			material = materials[strings.Join(fields[1:], " ")]
+		case "f":
+			if len(fields) < 4 {
+				return fmt.Errorf("face needs at least 3 vertices, got %d", len(fields)-1)
+			}
+
+			face := make([]faceVertex, len(fields)-1)
+			for i, ref := range fields[1:] {
+				fv, err := parseFaceVertex(ref, len(positions), len(texs), len(normals))
+				if err != nil {
+					return fmt.Errorf("vertex %q: %w", ref, err)
+				}
+				if i > 0 && ((fv.vt < 0) != (face[0].vt < 0) || (fv.vn < 0) != (face[0].vn < 0)) {
+					return fmt.Errorf("vertex %q: %w", ref, errMixedFormat)
+				}
+				face[i] = fv
+			}
+
+			corners := make([]int32, len(face))
+			for i, fv := range face {
+				index, ok := shared[fv]
+				if !ok {
+					v := vertex{p: positions[fv.v], c: colors[fv.v]}
+					if fv.vt >= 0 {
+						v.t = texs[fv.vt]
+					}
+					if fv.vn >= 0 {
+						v.n = normals[fv.vn]
+					}
+					index = int32(len(m.vertices))
+					m.vertices = append(m.vertices, v)
+					shared[fv] = index
+				}
+				corners[i] = index
+			}
+
+			for i := 1; i < len(face)-1; i++ {
+				m.indices = append(m.indices, corners[0], corners[i], corners[i+1])
+				m.mats = append(m.mats, material)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// parseFaceVertex parses a single face reference given the number of
+// vertices, texture coordinates and normals read so far.
+func parseFaceVertex(ref string, nv, nvt, nvn int) (faceVertex, error) {
+	fv := faceVertex{v: -1, vt: -1, vn: -1}
+
+	parts := strings.Split(ref, "/")
+	if len(parts) > 3 {
+		return fv, errors.New("too many components")
+	}
+
+	var err error
+	if fv.v, err = resolveIndex(parts[0], nv); err != nil {
+		return fv, err
+	}
+	if len(parts) > 1 && parts[1] != "" {
+		if fv.vt, err = resolveIndex(parts[1], nvt); err != nil {
+			return fv, err
+		}
+	}
+	if len(parts) > 2 {
+		if fv.vn, err = resolveIndex(parts[2], nvn); err != nil {
+			return fv, err
+		}
+	}
+	return fv, nil
+}
+
+func parseFloats(fields []string) ([]float64, error) {
+	values := make([]float64, len(fields))
+	for i, field := range fields {
+		f, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = f
+	}
+	return values, nil
+}
+
+// resolveIndex turns a 1-based OBJ index into a 0-based slice index. Negative
+// indices count backwards from the last of the n elements read so far.
+func resolveIndex(s string, n int) (int, error) {
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, err
+	}
+	if i < 0 {
+		i += n
+	} else {
+		i--
+	}
+	if i < 0 || i >= n {
+		return 0, errIndexOutOfRange
+	}
+	return i, nil
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseOBJ(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		tris int
+	}{
+		{"triangle", "v 0 0 0\nv 1 0 0\nv 0 1 0\nf 1 2 3\n", 1},
+		{"whitespace", "v\t0  0 0  \n  v 1\t0 0\nv 0 1 0\r\nf  1\t2 3 \n", 1},
+		{"comments", "# header\nv 0 0 0 # origin\nv 1 0 0\nv 0 1 0\nf 1 2 3 # face\n", 1},
+		{"negative indices", "v 0 0 0\nv 1 0 0\nv 0 1 0\nf -3 -2 -1\n", 1},
+		{"quad", "v 0 0 0\nv 1 0 0\nv 1 1 0\nv 0 1 0\nf 1 2 3 4\n", 2},
+		{"hexagon", "v 0 0 0\nv 1 0 0\nv 2 1 0\nv 1 2 0\nv 0 2 0\nv -1 1 0\nf 1 2 3 4 5 6\n", 4},
+		{"textured", "v 0 0 0\nv 1 0 0\nv 0 1 0\nvt 0 0\nvt 1 0\nvt 0 1\nf 1/1 2/2 3/3\n", 1},
+		{"textured with normals", "v 0 0 0\nv 1 0 0\nv 0 1 0\nvt 0 0\nvn 0 0 1\nf 1/1/1 2/1/1 3/1/1\n", 1},
+		{"ignored statements", "mtllib a.mtl\no Cube\ns off\nusemtl A\nv 0 0 0\nv 1 0 0\nv 0 1 0\nf 1 2 3\n", 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m, err := parseOBJ(strings.NewReader(test.src), "test.obj", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.triangles() != test.tris {
+				t.Fatalf("got %d triangles, want %d", m.triangles(), test.tris)
+			}
+		})
+	}
+}
+
+func TestParseOBJNegativeIndices(t *testing.T) {
+	src := "v 0 0 0\nv 1 0 0\nv 0 1 0\nf 1 2 3\nv 5 5 5\nf -4 -1 -2\n"
+	m, err := parseOBJ(strings.NewReader(src), "test.obj", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [3]vec3d{{0, 0, 0, 1}, {5, 5, 5, 1}, {0, 1, 0, 1}}
+	if got := m.triangle(1).p; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestParseOBJFaceFormats(t *testing.T) {
+	header := "v 0 0 0\nv 1 0 0\nv 0 1 0\nvt 0.25 0.5\nvt 1 0\nvt 0 1\nvn 0 0 -1\n"
+	tests := []struct {
+		face       string
+		hasTexture bool
+		hasNormals bool
+	}{
+		{"f 1 2 3", false, false},
+		{"f 1/1 2/2 3/3", true, false},
+		{"f 1//1 2//1 3//1", false, true},
+		{"f 1/1/1 2/2/1 3/3/1", true, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.face, func(t *testing.T) {
+			m, err := parseOBJ(strings.NewReader(header+test.face+"\n"), "test.obj", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tri := m.triangle(0)
+
+			wantUV := vec2d{}
+			if test.hasTexture {
+				wantUV = vec2d{0.25, 0.5, 1}
+			}
+			if tri.t[0] != wantUV {
+				t.Errorf("got uv %v, want %v", tri.t[0], wantUV)
+			}
+
+			wantNormal := vec3d{}
+			if test.hasNormals {
+				wantNormal = vec3d{0, 0, -1, 0}
+			}
+			if n := tri.v[0].vec3(varyingNormal); n != wantNormal {
+				t.Errorf("got normal %v, want %v", n, wantNormal)
+			}
+		})
+	}
+}
+
+func TestParseOBJSharedVertices(t *testing.T) {
+	header := "v 0 0 0\nv 1 0 0\nv 1 1 0\nv 0 1 0\nvt 0 0\nvt 1 1\n"
+	tests := []struct {
+		name     string
+		faces    string
+		vertices int
+		indices  []int32
+	}{
+		{"quad", "f 1 2 3 4\n", 4, []int32{0, 1, 2, 0, 2, 3}},
+		{"two faces", "f 1 2 3\nf 1 3 4\n", 4, []int32{0, 1, 2, 0, 2, 3}},
+		{"same texture coordinates", "f 1/1 2/1 3/1\nf 1/1 3/1 4/1\n", 4, []int32{0, 1, 2, 0, 2, 3}},
+		{"split texture coordinates", "f 1/1 2/1 3/1\nf 1/2 3/1 4/1\n", 5, []int32{0, 1, 2, 3, 2, 4}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m, err := parseOBJ(strings.NewReader(header+test.faces), "test.obj", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(m.vertices) != test.vertices {
+				t.Errorf("got %d vertices, want %d", len(m.vertices), test.vertices)
+			}
+			if !slices.Equal(m.indices, test.indices) {
+				t.Errorf("got indices %v, want %v", m.indices, test.indices)
+			}
+		})
+	}
+}
+
+func TestParseOBJVertexColors(t *testing.T) {
+	src := "v 0 0 0 1 0 0\nv 1 0 0 0 0.5 1\nv 0 1 0\nf 1 2 3\n"
+	m, err := parseOBJ(strings.NewReader(src), "test.obj", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][4]float64{{1, 0, 0, 1}, {0, 0.5, 1, 1}, white}
+	for i, v := range m.vertices {
+		if v.c != want[i] {
+			t.Errorf("vertex %d: got color %v, want %v", i, v.c, want[i])
+		}
+	}
+}
+
+func TestParseOBJErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		line int
+		err  error
+	}{
+		{"short vertex", "v 0 0\n", 1, nil},
+		{"bad float", "v 0 0 0\nv 1 x 0\n", 2, nil},
+		{"short face", "v 0 0 0\nv 1 0 0\nf 1 2\n", 3, nil},
+		{"index out of range", "v 0 0 0\nv 1 0 0\nv 0 1 0\n\nf 1 2 4\n", 5, errIndexOutOfRange},
+		{"zero index", "v 0 0 0\nv 1 0 0\nv 0 1 0\nf 0 1 2\n", 4, errIndexOutOfRange},
+		{"negative out of range", "v 0 0 0\nv 1 0 0\nv 0 1 0\nf -4 1 2\n", 4, errIndexOutOfRange},
+		{"mixed formats", "v 0 0 0\nv 1 0 0\nv 0 1 0\nvt 0 0\nf 1/1 2 3\n", 5, errMixedFormat},
+		{"normal out of range", "v 0 0 0\nv 1 0 0\nv 0 1 0\nvn 0 0 1\nf 1//1 2//1 3//2\n", 5, errIndexOutOfRange},
+		{"texture out of range", "v 0 0 0\nv 1 0 0\nv 0 1 0\nvt 0 0\nf 1/1 2/2 3/1\n", 5, errIndexOutOfRange},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := parseOBJ(strings.NewReader(test.src), "test.obj", nil)
+
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("got %v, want a *ParseError", err)
+			}
+			if parseErr.Filename != "test.obj" || parseErr.Line != test.line {
+				t.Errorf("got position %s:%d, want test.obj:%d", parseErr.Filename, parseErr.Line, test.line)
+			}
+			if test.err != nil && !errors.Is(err, test.err) {
+				t.Errorf("got %v, want %v", err, test.err)
+			}
+		})
+	}
+}
+
+func TestLoadBundledModels(t *testing.T) {
+	models := []string{
+		"axis.obj",
+		"cube2.obj",
+		"cube3.obj",
+		"cube4.obj",
+		"export.obj",
+		"Level1.obj",
+		"mountains.obj",
+		"ship.obj",
+		"teapot.obj",
+	}
+
+	for _, model := range models {
+		m := mesh{}
+		if err := m.Load(model, NewTextureLoader(nil)); err != nil {
+			t.Errorf("%s: %v", model, err)
+		} else if m.triangles() == 0 {
+			t.Errorf("%s: no triangles loaded", model)
+		}
+	}
+}
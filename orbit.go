@@ -0,0 +1,85 @@
+package main
+
+import "math"
+
+const (
+	// minOrbitDistance keeps zooming from moving the camera onto its target.
+	minOrbitDistance = 1e-3
+	// panSensitivity is the pan per pixel of cursor motion, relative to the
+	// distance from the target.
+	panSensitivity = 0.002
+	// zoomStep is the change of the distance per step of the wheel.
+	zoomStep = 1.1
+)
+
+// orbitCamera circles a target point for inspecting a model. Yaw and pitch
+// give the direction the camera looks at the target from, with the same
+// sense as for the free-look camera.
+type orbitCamera struct {
+	target     vec3d
+	distance   float64
+	yaw, pitch float64
+}
+
+// camera returns the free-look camera at the orbit camera's pose.
+func (o *orbitCamera) camera() camera {
+	c := camera{yaw: o.yaw, pitch: o.pitch}
+	vForward := c.forward()
+	vBack := vForward.Mul(-o.distance)
+	c.pos = o.target.Add(&vBack)
+	return c
+}
+
+func (o *orbitCamera) viewMatrix() mat4x4 {
+	c := o.camera()
+	return c.viewMatrix()
+}
+
+// rotate moves the camera around the target and clamps its pitch.
+func (o *orbitCamera) rotate(yaw, pitch float64) {
+	o.yaw = math.Remainder(o.yaw+yaw, 2*math.Pi)
+	o.pitch = math.Max(-maxPitch, math.Min(maxPitch, o.pitch+pitch))
+}
+
+// zoom multiplies the distance to the target by factor.
+func (o *orbitCamera) zoom(factor float64) {
+	o.distance = math.Max(minOrbitDistance, o.distance*factor)
+}
+
+// pan moves the target, and the camera with it, along the camera's right
+// and up axes.
+func (o *orbitCamera) pan(right, up float64) {
+	c := o.camera()
+	vRight := c.right()
+	vUp := c.up()
+	vRight = vRight.Mul(right)
+	vUp = vUp.Mul(up)
+
+	o.target = o.target.Add(&vRight)
+	o.target = o.target.Add(&vUp)
+}
+
+// update zooms by the wheel and rotates or pans while the cursor is dragged.
+func (o *orbitCamera) update(a *actions) {
+	o.zoom(math.Pow(zoomStep, -a[actionZoom]))
+
+	if a[actionOrbit] != 0 {
+		o.rotate(a[actionLookX]*mouseSensitivity, a[actionLookY]*mouseSensitivity)
+	} else if a[actionPan] != 0 {
+		scale := o.distance * panSensitivity
+		o.pan(-a[actionLookX]*scale, -a[actionLookY]*scale)
+	}
+}
+
+// fit aims the camera at the center of a bounding sphere and backs off until
+// the sphere fits the field of view of the projection matProj, made by
+// matrixMakeProjection. The narrower of the horizontal and vertical field of
+// view decides.
+func (o *orbitCamera) fit(center vec3d, radius float64, matProj *mat4x4) {
+	// m[0][0] and m[1][1] are the cotangents of half the horizontal and
+	// vertical field of view
+	halfFov := math.Atan(1 / math.Max(matProj.m[0][0], matProj.m[1][1]))
+
+	o.target = center
+	o.distance = math.Max(minOrbitDistance, radius/math.Sin(halfFov))
+}
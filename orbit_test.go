@@ -0,0 +1,110 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMeshBoundingSphere(t *testing.T) {
+	var cube mesh
+	cube.LoadCube()
+	center, radius := cube.boundingSphere()
+	if want := (vec3d{0.5, 0.5, 0, 1}); !vectorsEqual(center, want) {
+		t.Errorf("center: got %v, want %v", center, want)
+	}
+	if want := math.Sqrt(0.5); math.Abs(radius-want) > matrixEpsilon {
+		t.Errorf("radius: got %g, want %g", radius, want)
+	}
+
+	teapot := mesh{}
+	if err := teapot.Load("teapot.obj", nil); err != nil {
+		t.Fatal(err)
+	}
+	center, radius = teapot.boundingSphere()
+	for _, v := range teapot.vertices {
+		d := v.p.Sub(&center)
+		if d.Length() > radius+matrixEpsilon {
+			t.Fatalf("vertex %v is outside the sphere around %v with radius %g", v.p, center, radius)
+		}
+	}
+
+	var empty mesh
+	if _, radius := empty.boundingSphere(); radius != 0 {
+		t.Errorf("empty mesh: got radius %g, want 0", radius)
+	}
+}
+
+func TestOrbitCameraLooksAtTarget(t *testing.T) {
+	tests := []orbitCamera{
+		{target: vec3d{0, 0, 0, 1}, distance: 5},
+		{target: vec3d{1, 2, 3, 1}, distance: 2, yaw: 1, pitch: -0.5},
+		{target: vec3d{-3, 0, 1, 1}, distance: 10, yaw: -2.5, pitch: 1.2},
+	}
+
+	for _, o := range tests {
+		matView := o.viewMatrix()
+		got := matView.matrixMultiplyVector(&o.target)
+		if want := (vec3d{0, 0, o.distance, 1}); !vectorsEqual(got, want) {
+			t.Errorf("%+v: target maps to %v, want %v", o, got, want)
+		}
+
+		before := o.camera()
+		o.pan(0.5, -1)
+		after := o.camera()
+		moved := after.pos.Sub(&before.pos)
+		moved.w = 0
+		right, up := before.right(), before.up()
+		want := right.Mul(0.5)
+		down := up.Mul(-1)
+		want = want.Add(&down)
+		if !vectorsEqual(moved, want) {
+			t.Errorf("%+v: pan moved the camera by %v, want %v", o, moved, want)
+		}
+	}
+}
+
+func TestOrbitCameraLimits(t *testing.T) {
+	o := orbitCamera{distance: 1}
+	o.zoom(0)
+	if o.distance != minOrbitDistance {
+		t.Errorf("zoom: got distance %g, want %g", o.distance, minOrbitDistance)
+	}
+	o.rotate(0, 10)
+	if o.pitch != maxPitch {
+		t.Errorf("rotate: got pitch %g, want %g", o.pitch, maxPitch)
+	}
+}
+
+func TestOrbitCameraFit(t *testing.T) {
+	sizes := []struct{ w, h int }{{256, 256}, {320, 180}, {180, 320}}
+	center, radius := vec3d{1, -2, 3, 1}, 1.5
+
+	for _, size := range sizes {
+		matProj := defaultProjection(size.w, size.h, 90)
+		o := orbitCamera{yaw: 0.3, pitch: 0.2}
+		o.fit(center, radius, &matProj)
+		c := o.camera()
+		matView := c.viewMatrix()
+
+		// the sphere's silhouette is where the view rays are tangent to it,
+		// this far from its center across the view direction
+		vCenter := matView.matrixMultiplyVector(&center)
+		d := vCenter.Length()
+		tangent := radius * d / math.Sqrt(d*d-radius*radius)
+
+		// the silhouette must land on screen and touch its edge along the
+		// narrower axis
+		extent := 0.0
+		for _, axis := range []vec3d{c.right(), c.up()} {
+			edge := axis.Mul(tangent)
+			p := center.Add(&edge)
+			p = matView.matrixMultiplyVector(&p)
+			p = matProj.matrixMultiplyVector(&p)
+			p.ScaleW()
+			extent = max(extent, math.Abs(p.x), math.Abs(p.y))
+		}
+		if math.Abs(extent-1) > 1e-6 {
+			t.Errorf("%dx%d: sphere reaches %g of the screen, want 1", size.w, size.h, extent)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ParseError is returned when a text file, such as an OBJ model, an MTL
+// material library, a bindings file or an input recording, can not be
+// parsed. It carries the position of the offending line, or 0 if the file
+// as a whole is at fault.
+type ParseError struct {
+	Filename string
+	Line     int
+	Err      error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s:%d: %v", e.Filename, e.Line, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// scanLines calls fn with the number and the whitespace separated fields of
+// every line of r that is not blank once comments are stripped. A comment
+// starts with a field beginning with #, so # inside names such as texture
+// file names is kept. Errors returned by fn or met reading r are returned as
+// *ParseError for the line they occurred on.
+func scanLines(r io.Reader, filename string, fn func(line int, fields []string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		fields := strings.Fields(scanner.Text())
+		for i, field := range fields {
+			if strings.HasPrefix(field, "#") {
+				fields = fields[:i]
+				break
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		if err := fn(lineNumber, fields); err != nil {
+			return &ParseError{Filename: filename, Line: lineNumber, Err: err}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return &ParseError{Filename: filename, Line: lineNumber + 1, Err: err}
+	}
+	return nil
+}
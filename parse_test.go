@@ -0,0 +1,41 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScanLines(t *testing.T) {
+	src := "# header\n\na b  c\n   \nd # trailing\n#e\nf\nmap_Kd tex#1.png #comment\n"
+
+	var lines []int
+	var fields [][]string
+	err := scanLines(strings.NewReader(src), "test.txt", func(line int, f []string) error {
+		lines = append(lines, line)
+		fields = append(fields, f)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{3, 5, 7, 8}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("got lines %v, want %v", lines, want)
+	}
+	if want := [][]string{{"a", "b", "c"}, {"d"}, {"f"}, {"map_Kd", "tex#1.png"}}; !reflect.DeepEqual(fields, want) {
+		t.Errorf("got fields %q, want %q", fields, want)
+	}
+
+	errStop := errors.New("stop")
+	err = scanLines(strings.NewReader(src), "test.txt", func(line int, f []string) error {
+		if f[0] == "d" {
+			return errStop
+		}
+		return nil
+	})
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || parseErr.Line != 5 || parseErr.Filename != "test.txt" || !errors.Is(err, errStop) {
+		t.Errorf("got %v, want errStop at test.txt:5", err)
+	}
+}
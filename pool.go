@@ -0,0 +1,64 @@
+package main
+
+import "sync"
+
+// stage is a parallel part of a frame that pool workers can run.
+type stage int
+
+const (
+	stageTransform stage = iota
+	stageVertices
+	stageTiles
+)
+
+type poolJob struct {
+	r     *Renderer
+	stage stage
+}
+
+// workerPool is a set of long lived goroutines shared by all renderers.
+// Starting goroutines every frame would allocate, so the pool only starts new
+// ones when a renderer asks for more workers than it has.
+type workerPool struct {
+	mu   sync.Mutex
+	size int
+	jobs chan poolJob
+}
+
+var pool = workerPool{jobs: make(chan poolJob)}
+
+// run has workers goroutines of the pool run stage s of r's frame and waits
+// for them to finish. The workers share r.next to hand out the stage's work.
+func (p *workerPool) run(r *Renderer, s stage, workers int) {
+	p.grow(workers)
+
+	r.next.Store(0)
+	r.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		p.jobs <- poolJob{r: r, stage: s}
+	}
+	r.wg.Wait()
+}
+
+func (p *workerPool) grow(size int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for ; p.size < size; p.size++ {
+		go p.work()
+	}
+}
+
+func (p *workerPool) work() {
+	for j := range p.jobs {
+		switch j.stage {
+		case stageTransform:
+			j.r.transformWorker()
+		case stageVertices:
+			j.r.vertexWorker()
+		case stageTiles:
+			j.r.tileWorker()
+		}
+		j.r.wg.Done()
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import "math"
+
+// quat is a rotation quaternion w + xi + yj + zk. Rotations built from
+// quaternions agree with the matrices of matrix.go, q.Matrix() of an axis and
+// angle equals matrixMakeRotationAxis for the same axis and angle.
+type quat struct {
+	w, x, y, z float64
+}
+
+func quatIdentity() quat {
+	return quat{w: 1}
+}
+
+// quatFromAxisAngle rotates by angleRad around axis, which need not be
+// normalized.
+func quatFromAxisAngle(axis vec3d, angleRad float64) quat {
+	axis.Normalize()
+	s := math.Sin(angleRad / 2)
+	return quat{math.Cos(angleRad / 2), axis.x * s, axis.y * s, axis.z * s}
+}
+
+// quatFromEuler rolls around z first, then pitches around x and finally
+// yaws around y, with the angles in the sense of matrixMakeRotationZ,
+// matrixMakeRotationX and matrixMakeRotationY.
+func quatFromEuler(pitch, yaw, roll float64) quat {
+	qPitch := quatFromAxisAngle(vec3d{1, 0, 0, 0}, pitch)
+	qYaw := quatFromAxisAngle(vec3d{0, 1, 0, 0}, -yaw)
+	qRoll := quatFromAxisAngle(vec3d{0, 0, 1, 0}, roll)
+	q := qPitch.Mul(&qRoll)
+	return qYaw.Mul(&q)
+}
+
+// quatFromMatrix returns the rotation held by the upper 3x3 part of m, which
+// must be a pure rotation.
+func quatFromMatrix(m *mat4x4) quat {
+	a := &m.m
+	var q quat
+	if trace := a[0][0] + a[1][1] + a[2][2]; trace > 0 {
+		s := 0.5 / math.Sqrt(trace+1)
+		q = quat{0.25 / s, (a[1][2] - a[2][1]) * s, (a[2][0] - a[0][2]) * s, (a[0][1] - a[1][0]) * s}
+	} else if a[0][0] > a[1][1] && a[0][0] > a[2][2] {
+		s := 2 * math.Sqrt(1+a[0][0]-a[1][1]-a[2][2])
+		q = quat{(a[1][2] - a[2][1]) / s, 0.25 * s, (a[0][1] + a[1][0]) / s, (a[0][2] + a[2][0]) / s}
+	} else if a[1][1] > a[2][2] {
+		s := 2 * math.Sqrt(1+a[1][1]-a[0][0]-a[2][2])
+		q = quat{(a[2][0] - a[0][2]) / s, (a[0][1] + a[1][0]) / s, 0.25 * s, (a[1][2] + a[2][1]) / s}
+	} else {
+		s := 2 * math.Sqrt(1+a[2][2]-a[0][0]-a[1][1])
+		q = quat{(a[0][1] - a[1][0]) / s, (a[0][2] + a[2][0]) / s, (a[1][2] + a[2][1]) / s, 0.25 * s}
+	}
+	q.Normalize()
+	return q
+}
+
+// Mul returns the rotation that rotates by q2 first and then by q, so
+// q.Mul(q2).Matrix() equals q2.Matrix() multiplied by q.Matrix().
+func (q *quat) Mul(q2 *quat) quat {
+	return quat{
+		w: q.w*q2.w - q.x*q2.x - q.y*q2.y - q.z*q2.z,
+		x: q.w*q2.x + q.x*q2.w + q.y*q2.z - q.z*q2.y,
+		y: q.w*q2.y - q.x*q2.z + q.y*q2.w + q.z*q2.x,
+		z: q.w*q2.z + q.x*q2.y - q.y*q2.x + q.z*q2.w,
+	}
+}
+
+func (q *quat) Dot(q2 *quat) float64 {
+	return q.w*q2.w + q.x*q2.x + q.y*q2.y + q.z*q2.z
+}
+
+func (q *quat) Length() float64 {
+	return math.Sqrt(q.Dot(q))
+}
+
+func (q *quat) Normalize() {
+	l := q.Length()
+	q.w /= l
+	q.x /= l
+	q.y /= l
+	q.z /= l
+}
+
+// Conjugate returns the inverse rotation of the unit quaternion q.
+func (q *quat) Conjugate() quat {
+	return quat{q.w, -q.x, -q.y, -q.z}
+}
+
+// Rotate rotates v by the unit quaternion q, keeping its w.
+func (q *quat) Rotate(v *vec3d) vec3d {
+	// v + 2w(u x v) + 2u x (u x v) with u the vector part of q
+	u := vec3d{q.x, q.y, q.z, 0}
+	uv := u.CrossProduct(v)
+	uuv := u.CrossProduct(&uv)
+	uv = uv.Mul(2 * q.w)
+	uuv = uuv.Mul(2)
+	r := v.Add(&uv)
+	r = r.Add(&uuv)
+	r.w = v.w
+	return r
+}
+
+// Matrix returns the rotation matrix of the unit quaternion q.
+func (q *quat) Matrix() mat4x4 {
+	w, x, y, z := q.w, q.x, q.y, q.z
+	m := matrixMakeIdentity()
+	m.m[0][0] = 1 - 2*(y*y+z*z)
+	m.m[0][1] = 2 * (x*y + z*w)
+	m.m[0][2] = 2 * (x*z - y*w)
+	m.m[1][0] = 2 * (x*y - z*w)
+	m.m[1][1] = 1 - 2*(x*x+z*z)
+	m.m[1][2] = 2 * (y*z + x*w)
+	m.m[2][0] = 2 * (x*z + y*w)
+	m.m[2][1] = 2 * (y*z - x*w)
+	m.m[2][2] = 1 - 2*(x*x+y*y)
+	return m
+}
+
+// Euler returns the angles quatFromEuler builds q from. Pitch is in
+// [-pi/2, pi/2]. At a pitch of +-pi/2 yaw and roll turn around the same
+// axis, all of the rotation is then reported as yaw.
+func (q *quat) Euler() (pitch, yaw, roll float64) {
+	m := q.Matrix()
+	a := &m.m
+	pitch = math.Asin(math.Max(-1, math.Min(1, -a[2][1])))
+	if math.Abs(a[2][1]) > 1-1e-9 {
+		return pitch, math.Atan2(a[0][2], a[0][0]), 0
+	}
+	return pitch, math.Atan2(-a[2][0], a[2][2]), math.Atan2(a[0][1], a[1][1])
+}
+
+// quatNlerp interpolates linearly between the unit quaternions q1 and q2
+// along the shorter arc and normalizes the result. It is cheaper than
+// quatSlerp but does not turn at a constant rate.
+func quatNlerp(q1, q2 *quat, t float64) quat {
+	to := *q2
+	if q1.Dot(q2) < 0 {
+		to = quat{-to.w, -to.x, -to.y, -to.z}
+	}
+	q := quat{
+		w: q1.w + t*(to.w-q1.w),
+		x: q1.x + t*(to.x-q1.x),
+		y: q1.y + t*(to.y-q1.y),
+		z: q1.z + t*(to.z-q1.z),
+	}
+	q.Normalize()
+	return q
+}
+
+// quatSlerp interpolates between the unit quaternions q1 and q2 along the
+// shorter arc at a constant angular rate.
+func quatSlerp(q1, q2 *quat, t float64) quat {
+	to := *q2
+	cos := q1.Dot(q2)
+	if cos < 0 {
+		to = quat{-to.w, -to.x, -to.y, -to.z}
+		cos = -cos
+	}
+	// nearly the same rotation, the sine below would divide by almost zero
+	if cos > 1-1e-9 {
+		return quatNlerp(q1, &to, t)
+	}
+
+	angle := math.Acos(cos)
+	s1 := math.Sin((1-t)*angle) / math.Sin(angle)
+	s2 := math.Sin(t*angle) / math.Sin(angle)
+	return quat{
+		w: s1*q1.w + s2*to.w,
+		x: s1*q1.x + s2*to.x,
+		y: s1*q1.y + s2*to.y,
+		z: s1*q1.z + s2*to.z,
+	}
+}
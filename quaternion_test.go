@@ -0,0 +1,155 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// quatsEqual reports whether q1 and q2 are the same rotation, q and -q are.
+func quatsEqual(q1, q2 quat) bool {
+	return math.Abs(math.Abs(q1.Dot(&q2))-1) < matrixEpsilon
+}
+
+var quatAxisAngles = []struct {
+	axis  vec3d
+	angle float64
+}{
+	{vec3d{1, 0, 0, 0}, 0.5},
+	{vec3d{0, 1, 0, 0}, -1.2},
+	{vec3d{0, 0, 3, 0}, math.Pi},
+	{vec3d{1, 2, 3, 0}, 2.5},
+	{vec3d{-1, 0.5, 0.2, 0}, -3},
+}
+
+func TestQuatMatrixMatchesAxisAngle(t *testing.T) {
+	for _, test := range quatAxisAngles {
+		q := quatFromAxisAngle(test.axis, test.angle)
+		got := q.Matrix()
+		want := matrixMakeRotationAxis(test.axis, test.angle)
+		if !matricesEqual(&got, &want) {
+			t.Errorf("axis %v, angle %g: got %v, want %v", test.axis, test.angle, got, want)
+		}
+
+		if back := quatFromMatrix(&got); !quatsEqual(back, q) {
+			t.Errorf("axis %v, angle %g: quatFromMatrix got %v, want %v", test.axis, test.angle, back, q)
+		}
+
+		v := vec3d{0.3, -2, 5, 1}
+		if got, want := q.Rotate(&v), want.matrixMultiplyVector(&v); !vectorsEqual(got, want) {
+			t.Errorf("axis %v, angle %g: rotated %v to %v, want %v", test.axis, test.angle, v, got, want)
+		}
+	}
+}
+
+func TestQuatMul(t *testing.T) {
+	for i := range quatAxisAngles {
+		for j := range quatAxisAngles {
+			q1 := quatFromAxisAngle(quatAxisAngles[i].axis, quatAxisAngles[i].angle)
+			q2 := quatFromAxisAngle(quatAxisAngles[j].axis, quatAxisAngles[j].angle)
+
+			q := q1.Mul(&q2)
+			got := q.Matrix()
+			m1, m2 := q1.Matrix(), q2.Matrix()
+			want := m2.multiplyMatrix(&m1)
+			if !matricesEqual(&got, &want) {
+				t.Errorf("%d * %d: got %v, want %v", i, j, got, want)
+			}
+		}
+	}
+
+	q := quatFromAxisAngle(vec3d{1, 2, 3, 0}, 0.8)
+	inv := q.Conjugate()
+	if got := q.Mul(&inv); !quatsEqual(got, quatIdentity()) {
+		t.Errorf("q * conjugate = %v, want identity", got)
+	}
+}
+
+func TestQuatEuler(t *testing.T) {
+	tests := []struct {
+		pitch, yaw, roll float64
+	}{
+		{0, 0, 0},
+		{0.3, 0, 0},
+		{0, 0.3, 0},
+		{0, 0, 0.3},
+		{0.4, -2.5, 1.1},
+		{-1.5, 3, -0.2},
+	}
+
+	for _, test := range tests {
+		q := quatFromEuler(test.pitch, test.yaw, test.roll)
+
+		got := q.Matrix()
+		roll := matrixMakeRotationZ(test.roll)
+		pitch := matrixMakeRotationX(test.pitch)
+		yaw := matrixMakeRotationY(test.yaw)
+		want := roll.multiplyMatrix(&pitch)
+		want = want.multiplyMatrix(&yaw)
+		if !matricesEqual(&got, &want) {
+			t.Errorf("%v: got %v, want %v", test, got, want)
+		}
+
+		p, y, r := q.Euler()
+		if math.Abs(p-test.pitch) > matrixEpsilon || math.Abs(y-test.yaw) > matrixEpsilon || math.Abs(r-test.roll) > matrixEpsilon {
+			t.Errorf("%v: got angles %g, %g, %g", test, p, y, r)
+		}
+	}
+
+	// looking straight up yaw and roll turn around the same axis
+	q := quatFromEuler(math.Pi/2, 0.5, 0.25)
+	p, y, r := q.Euler()
+	back := quatFromEuler(p, y, r)
+	if !quatsEqual(back, q) || r != 0 {
+		t.Errorf("gimbal lock: got angles %g, %g, %g for %v", p, y, r, q)
+	}
+}
+
+func TestQuatSlerp(t *testing.T) {
+	axis := vec3d{1, 1, 0, 0}
+	q1 := quatFromAxisAngle(axis, 0.2)
+	q2 := quatFromAxisAngle(axis, 1.8)
+
+	tests := []struct {
+		t     float64
+		angle float64
+	}{
+		{0, 0.2},
+		{0.25, 0.6},
+		{0.5, 1.0},
+		{1, 1.8},
+	}
+	for _, test := range tests {
+		want := quatFromAxisAngle(axis, test.angle)
+		if got := quatSlerp(&q1, &q2, test.t); !quatsEqual(got, want) {
+			t.Errorf("slerp at %g: got %v, want %v", test.t, got, want)
+		}
+	}
+
+	// q and -q are the same rotation, slerp must not take the long way
+	neg := quat{-q2.w, -q2.x, -q2.y, -q2.z}
+	if got, want := quatSlerp(&q1, &neg, 0.5), quatFromAxisAngle(axis, 1.0); !quatsEqual(got, want) {
+		t.Errorf("slerp to -q: got %v, want %v", got, want)
+	}
+	if got := quatSlerp(&q1, &q1, 0.5); !quatsEqual(got, q1) {
+		t.Errorf("slerp between equal rotations: got %v, want %v", got, q1)
+	}
+}
+
+func TestQuatNlerp(t *testing.T) {
+	axis := vec3d{0, 0, 1, 0}
+	q1 := quatFromAxisAngle(axis, -0.5)
+	q2 := quatFromAxisAngle(axis, 0.5)
+
+	for _, test := range []struct {
+		t     float64
+		angle float64
+	}{{0, -0.5}, {0.5, 0}, {1, 0.5}} {
+		got := quatNlerp(&q1, &q2, test.t)
+		if math.Abs(got.Length()-1) > matrixEpsilon {
+			t.Errorf("nlerp at %g: got length %g, want 1", test.t, got.Length())
+		}
+		if want := quatFromAxisAngle(axis, test.angle); !quatsEqual(got, want) {
+			t.Errorf("nlerp at %g: got %v, want %v", test.t, got, want)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// runRender implements the render command: it loads a model, renders a
+// single frame from the given camera pose and writes it out as a PNG.
+func runRender(args []string) error {
+	flags := flag.NewFlagSet("render", flag.ContinueOnError)
+	objFile := flags.String("obj", "", "OBJ model to render, renders a textured quad if empty")
+	texFile := flags.String("tex", "", "texture image for faces without a material, uses the embedded texture if empty")
+	cam := flags.String("cam", "0,0,-4", "camera position as x,y,z")
+	fit := flags.Bool("fit", false, "ignore -cam and frame the whole model, looking from the direction of -yaw and -pitch")
+	fYaw := flags.Float64("yaw", 0, "camera yaw in radians")
+	fPitch := flags.Float64("pitch", 0, "camera pitch in radians, positive looks up")
+	fRoll := flags.Float64("roll", 0, "camera roll in radians")
+	fFov := flags.Float64("fov", 90, "vertical field of view in degrees")
+	size := flags.String("size", "256x256", "output size as WIDTHxHEIGHT")
+	filter := flags.String("filter", "nearest", "texture filtering: nearest or bilinear")
+	address := flags.String("address", "repeat", "texture addressing: repeat, mirror or clamp")
+	mipmap := flags.String("mipmap", "none", "mipmap filtering: none, nearest or linear")
+	raster := flags.String("raster", "scanline", "rasterizer: scanline or edge")
+	workers := flags.Int("workers", 0, "goroutines rasterizing in parallel, 0 uses GOMAXPROCS")
+	guardBand := flags.Float64("guardband", 1, "guard band size relative to the screen, 1 clips at the screen edges")
+	out := flags.String("out", "frame.png", "output PNG file")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	vCamera, err := parseVec3d(*cam)
+	if err != nil {
+		return fmt.Errorf("-cam: %w", err)
+	}
+	width, height, err := parseSize(*size)
+	if err != nil {
+		return fmt.Errorf("-size: %w", err)
+	}
+	if limit := maxGuardBand(width, height); math.IsNaN(*guardBand) || *guardBand > limit {
+		return fmt.Errorf("-guardband: must be at most %g at %dx%d, got %g", limit, width, height, *guardBand)
+	}
+
+	textureFilter, err := parseTextureFilter(*filter)
+	if err != nil {
+		return fmt.Errorf("-filter: %w", err)
+	}
+	addressMode, err := parseAddressMode(*address)
+	if err != nil {
+		return fmt.Errorf("-address: %w", err)
+	}
+	mipmapFilter, err := parseMipmapFilter(*mipmap)
+	if err != nil {
+		return fmt.Errorf("-mipmap: %w", err)
+	}
+	rasterizer, err := parseRasterizer(*raster)
+	if err != nil {
+		return fmt.Errorf("-raster: %w", err)
+	}
+
+	img, err := renderFrame(renderOptions{
+		objFile:    *objFile,
+		texFile:    *texFile,
+		vCamera:    vCamera,
+		fit:        *fit,
+		fYaw:       *fYaw,
+		fPitch:     *fPitch,
+		fRoll:      *fRoll,
+		fFov:       *fFov,
+		width:      width,
+		height:     height,
+		filter:     textureFilter,
+		address:    addressMode,
+		mipmap:     mipmapFilter,
+		rasterizer: rasterizer,
+		workers:    *workers,
+		guardBand:  *guardBand,
+	})
+	if err != nil {
+		return err
+	}
+
+	return writePNG(*out, img)
+}
+
+type renderOptions struct {
+	objFile    string
+	texFile    string
+	vCamera    vec3d
+	fit        bool
+	fYaw       float64
+	fPitch     float64
+	fRoll      float64
+	fFov       float64
+	width      int
+	height     int
+	filter     TextureFilter
+	address    AddressMode
+	mipmap     MipmapFilter
+	rasterizer Rasterizer
+	workers    int
+	guardBand  float64
+}
+
+// renderFrame loads the model and texture described by o and renders them
+// into a fresh framebuffer.
+func renderFrame(o renderOptions) (*image.RGBA, error) {
+	m, err := loadScene(o)
+	if err != nil {
+		return nil, err
+	}
+	return renderScene(m, o), nil
+}
+
+// loadScene loads the model described by o and textures it.
+func loadScene(o renderOptions) (*mesh, error) {
+	textures := NewTextureLoader(nil)
+	textures.Filter = o.filter
+	textures.Address = o.address
+	textures.Mipmap = o.mipmap
+
+	m := &mesh{}
+	if o.objFile == "" {
+		m.LoadCube()
+	} else if err := m.Load(o.objFile, textures); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case o.texFile != "":
+		tex, err := textures.Load(o.texFile)
+		if err != nil {
+			return nil, err
+		}
+		m.tex = tex
+	case m.needsTexture():
+		// the embedded texture is only decoded if some face shows it
+		tex := &TextureAtlasImpl{}
+		if err := tex.LoadTexture(); err != nil {
+			return nil, err
+		}
+		tex.SetFilter(o.filter)
+		tex.SetAddressMode(o.address)
+		tex.SetMipmapFilter(o.mipmap)
+		m.tex = tex
+	}
+	return m, nil
+}
+
+// renderScene renders m from the camera described by o into a fresh
+// framebuffer.
+func renderScene(m *mesh, o renderOptions) *image.RGBA {
+	matProj := defaultProjection(o.width, o.height, o.fFov)
+	matWorld := matrixMakeIdentity()
+	cam := newCamera(o.vCamera, o.fYaw)
+	if o.fit {
+		center, radius := m.boundingSphere()
+		orbit := orbitCamera{yaw: o.fYaw}
+		orbit.rotate(0, o.fPitch)
+		orbit.fit(center, radius, &matProj)
+		cam = orbit.camera()
+	} else {
+		cam.turn(0, o.fPitch, 0)
+	}
+	cam.turn(0, 0, o.fRoll)
+	matView := cam.viewMatrix()
+
+	renderer := NewRenderer(o.width, o.height, matProj)
+	renderer.SetRasterizer(o.rasterizer)
+	renderer.SetWorkers(o.workers)
+	renderer.SetGuardBand(o.guardBand)
+	renderer.Render(m, &matWorld, &matView, &cam.pos)
+	return renderer.Image()
+}
+
+func writePNG(filename string, img image.Image) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(file, img); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func parseTextureFilter(s string) (TextureFilter, error) {
+	switch s {
+	case "nearest":
+		return FilterNearest, nil
+	case "bilinear":
+		return FilterBilinear, nil
+	}
+	return 0, fmt.Errorf("unknown filter %q", s)
+}
+
+func parseAddressMode(s string) (AddressMode, error) {
+	switch s {
+	case "repeat":
+		return AddressRepeat, nil
+	case "mirror":
+		return AddressMirroredRepeat, nil
+	case "clamp":
+		return AddressClampToEdge, nil
+	}
+	return 0, fmt.Errorf("unknown address mode %q", s)
+}
+
+func parseMipmapFilter(s string) (MipmapFilter, error) {
+	switch s {
+	case "none":
+		return MipmapNone, nil
+	case "nearest":
+		return MipmapNearest, nil
+	case "linear":
+		return MipmapLinear, nil
+	}
+	return 0, fmt.Errorf("unknown mipmap filter %q", s)
+}
+
+func parseRasterizer(s string) (Rasterizer, error) {
+	switch s {
+	case "scanline":
+		return RasterizerScanline, nil
+	case "edge":
+		return RasterizerEdge, nil
+	}
+	return 0, fmt.Errorf("unknown rasterizer %q", s)
+}
+
+func parseVec3d(s string) (vec3d, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 3 {
+		return vec3d{}, fmt.Errorf("expected x,y,z, got %q", s)
+	}
+
+	var c [3]float64
+	for i, part := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return vec3d{}, err
+		}
+		c[i] = f
+	}
+	return vec3d{c[0], c[1], c[2], 1}, nil
+}
+
+func parseSize(s string) (int, int, error) {
+	width, height, ok := strings.Cut(s, "x")
+	if !ok {
+		return 0, 0, fmt.Errorf("expected WIDTHxHEIGHT, got %q", s)
+	}
+
+	w, err := strconv.Atoi(width)
+	if err != nil {
+		return 0, 0, err
+	}
+	h, err := strconv.Atoi(height)
+	if err != nil {
+		return 0, 0, err
+	}
+	if w <= 0 || h <= 0 {
+		return 0, 0, fmt.Errorf("size must be positive, got %q", s)
+	}
+	return w, h, nil
+}
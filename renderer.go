@@ -0,0 +1,592 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	clearColor = color.RGBA{
+		R: 32,
+		G: 32,
+		B: 32,
+		A: 255,
+	}
+
+	whiteTexture = newSolidTexture([3]float64{1, 1, 1})
+)
+
+// Renderer owns the color and depth buffers and rasterizes meshes into a
+// plain image.RGBA, so frames can be produced without a window or GPU context.
+// Pixels are written straight into the image's Pix slice, which a window can
+// upload in one go with WritePixels.
+type Renderer struct {
+	w, h              int
+	matProj           mat4x4
+	clearColor        color.RGBA
+	img               *image.RGBA
+	depthBuffer       []float64
+	trianglesToRaster []triangle
+	rasterList        []rasterTriangle
+	rasterizer        Rasterizer
+	// guardBand is how many times wider and higher than the screen the region
+	// is that triangles can extend into before they are clipped
+	guardBand float64
+	// workers is the number of goroutines processing vertices and
+	// rasterizing tiles in parallel, with 1 or less the frame is drawn on the
+	// calling goroutine
+	workers int
+	tiles   []tile
+	// vertexChunks holds the projected triangles of each chunk of the mesh
+	// between the parallel vertex stage and the merge
+	vertexChunks [][]triangle
+	vertexInput  vertexInput
+	vertexCache  []transformedVertex
+	// matNormal transforms the normals of the current frame's mesh
+	matNormal mat4x4
+	clipQueue triangleQueue
+	// next hands out chunks and tiles to the workers of a parallel stage
+	next atomic.Int32
+	wg   sync.WaitGroup
+}
+
+// Rasterizer selects the algorithm that fills projected triangles.
+type Rasterizer int
+
+const (
+	// RasterizerScanline walks the triangle's edges one scanline at a time
+	// at whole pixel precision.
+	RasterizerScanline Rasterizer = iota
+	// RasterizerEdge tests pixel centers against the triangle's edge
+	// functions with sub-pixel precision and a top-left fill rule, so
+	// triangles sharing an edge never leave gaps or overlap.
+	RasterizerEdge
+)
+
+func (r Rasterizer) String() string {
+	if r == RasterizerEdge {
+		return "edge"
+	}
+	return "scanline"
+}
+
+// defaultProjection builds the perspective projection used by both the window
+// and the render command for a w x h framebuffer and a field of view in degrees.
+func defaultProjection(w, h int, fFov float64) mat4x4 {
+	fNear := float64(0.1)
+	fFar := float64(1000)
+	fAspectRatio := float64(h) / float64(w)
+	fFovRad := 1.0 / math.Tan(fFov*0.5/180*math.Pi)
+
+	return matrixMakeProjection(fFovRad, fAspectRatio, fNear, fFar)
+}
+
+func NewRenderer(w, h int, matProj mat4x4) *Renderer {
+	return &Renderer{
+		w:           w,
+		h:           h,
+		matProj:     matProj,
+		clearColor:  clearColor,
+		img:         image.NewRGBA(image.Rect(0, 0, w, h)),
+		depthBuffer: make([]float64, w*h),
+		guardBand:   1,
+		workers:     runtime.GOMAXPROCS(0),
+		tiles:       makeTiles(w, h),
+	}
+}
+
+func (r *Renderer) Rasterizer() Rasterizer {
+	return r.rasterizer
+}
+
+func (r *Renderer) SetRasterizer(rasterizer Rasterizer) {
+	r.rasterizer = rasterizer
+}
+
+// defaultGuardBand is a guard band size that avoids most screen edge clipping
+// while keeping the corners of the triangles passed to the rasterizer close
+// enough to the screen that their spans stay cheap to scissor.
+const defaultGuardBand = 4
+
+// maxGuardBandPixels bounds the extent of the guard band in pixels. The edge
+// rasterizer multiplies fixed point coordinate differences that span up to
+// the guard band, and the sum of two such products must fit an int64.
+const maxGuardBandPixels = 1 << 22
+
+// maxGuardBand returns the largest guard band for a w x h screen.
+func maxGuardBand(w, h int) float64 {
+	return maxGuardBandPixels / float64(max(w, h))
+}
+
+func (r *Renderer) GuardBand() float64 {
+	return r.guardBand
+}
+
+// SetGuardBand sets the size of the guard band relative to the screen.
+// Triangles that cross the screen edges but stay inside it are scissored by
+// the rasterizer instead of being split by the clipper. The edge rasterizer
+// draws the same pixels either way, the scanline rasterizer snaps corners to
+// whole pixels, so its edges can move by a pixel. Values of 1 or less clip
+// every triangle at the screen edges, values above maxGuardBand are clamped
+// to it.
+func (r *Renderer) SetGuardBand(guardBand float64) {
+	if math.IsNaN(guardBand) {
+		guardBand = 1
+	}
+	r.guardBand = min(max(1, guardBand), maxGuardBand(r.w, r.h))
+}
+
+func (r *Renderer) Workers() int {
+	return r.workers
+}
+
+// SetWorkers sets how many goroutines process and rasterize the frame.
+// Values below 1 use GOMAXPROCS.
+func (r *Renderer) SetWorkers(workers int) {
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	r.workers = workers
+}
+
+// Image returns the framebuffer the last call to Render drew into.
+func (r *Renderer) Image() *image.RGBA {
+	return r.img
+}
+
+func (r *Renderer) Clear() {
+	for i := 0; i < len(r.img.Pix); i += 4 {
+		r.img.Pix[i+0] = r.clearColor.R
+		r.img.Pix[i+1] = r.clearColor.G
+		r.img.Pix[i+2] = r.clearColor.B
+		r.img.Pix[i+3] = r.clearColor.A
+	}
+	for i := range r.depthBuffer {
+		r.depthBuffer[i] = 0
+	}
+}
+
+// Render clears the buffers and draws m as seen from vCamera. Triangles
+// without a material are textured with the mesh's texture, or drawn white if
+// it has none. It returns the number of triangles that made it to the
+// rasterizer.
+func (r *Renderer) Render(m *mesh, matWorld, matView *mat4x4, vCamera *vec3d) int {
+	r.Clear()
+
+	meshTexture := m.tex
+	if meshTexture == nil {
+		meshTexture = whiteTexture
+	}
+
+	// draw triangles
+	r.processVertices(m, matWorld, matView, vCamera)
+
+	// sort triangles from back to front
+	/*
+		sort.Slice(r.trianglesToRaster, func(i, j int) bool {
+			z1 := (r.trianglesToRaster[i].p[0].z + r.trianglesToRaster[i].p[1].z + r.trianglesToRaster[i].p[2].z) / 3.0
+			z2 := (r.trianglesToRaster[j].p[0].z + r.trianglesToRaster[j].p[1].z + r.trianglesToRaster[j].p[2].z) / 3.0
+			return z1 > z2
+		})
+	*/
+
+	r.rasterList = r.rasterList[:0]
+
+	for i := range r.trianglesToRaster {
+		t := &r.trianglesToRaster[i]
+		texture := meshTexture
+		if t.mat != nil && t.mat.Texture != nil {
+			texture = t.mat.Texture
+		}
+		r.rasterList = append(r.rasterList, rasterTriangle{t: *t, tex: texture})
+	}
+
+	if r.workers > 1 {
+		r.rasterizeTiles()
+	} else {
+		for i := range r.rasterList {
+			r.rasterize(&r.rasterList[i], r.img.Bounds())
+		}
+	}
+
+	return len(r.rasterList)
+}
+
+// rasterTriangle is a projected, frustum clipped triangle together with the
+// texture to fill it with.
+type rasterTriangle struct {
+	t   triangle
+	tex TextureAtlas
+}
+
+// projectTriangle assembles the i-th triangle of m from the transformed
+// vertices in r.vertexCache, drops it if it faces away from vCamera, clips it
+// against the view frustum and appends the projected screen space triangles
+// to out. queue is scratch space for clipping.
+func (r *Renderer) projectTriangle(m *mesh, i int, vCamera *vec3d, queue *triangleQueue, out []triangle) []triangle {
+	var triClip triangle
+	var triTransformed triangle
+	var triViewed triangle
+
+	v0, v1, v2 := m.indices[3*i], m.indices[3*i+1], m.indices[3*i+2]
+	triTransformed.p[0] = r.vertexCache[v0].world
+	triTransformed.p[1] = r.vertexCache[v1].world
+	triTransformed.p[2] = r.vertexCache[v2].world
+	triTransformed.t = UVs{m.vertices[v0].t, m.vertices[v1].t, m.vertices[v2].t}
+
+	// NORMAL
+	normal := TNormal(&triTransformed)
+
+	vCameraRay := triTransformed.p[0].Sub(vCamera)
+	dp := normal.DotProduct(&vCameraRay)
+
+	if dp < 0 {
+		light_direction := vec3d{0, 1, -1, 1}
+		light_direction.Normalize()
+
+		// dp := normal.x*light_direction.x + normal.y*light_direction.y + normal.z*light_direction.z
+
+		triViewed.r = 20000
+		triViewed.g = 2000
+		triViewed.b = 2000
+		triViewed.a = 2000
+		triViewed.mat = m.mats[i]
+
+		// world to view space was done once per vertex
+		triViewed.p[0] = r.vertexCache[v0].view
+		triViewed.p[1] = r.vertexCache[v1].view
+		triViewed.p[2] = r.vertexCache[v2].view
+		triViewed.t = triTransformed.t.Copy()
+		for n, index := range [3]int32{v0, v1, v2} {
+			c := &r.vertexCache[index]
+			triViewed.v[n].setVec3(varyingNormal, c.normal)
+			triViewed.v[n].setColor(m.vertices[index].c)
+			triViewed.v[n].setVec3(varyingWorld, c.world)
+		}
+
+		// project from 3d into homogeneous clip space
+		triClip.p[0] = r.matProj.matrixMultiplyVector(&triViewed.p[0])
+		triClip.p[1] = r.matProj.matrixMultiplyVector(&triViewed.p[1])
+		triClip.p[2] = r.matProj.matrixMultiplyVector(&triViewed.p[2])
+		triClip.t = triViewed.t
+		triClip.v = triViewed.v
+		triClip.r = triViewed.r
+		triClip.g = triViewed.g
+		triClip.b = triViewed.b
+		triClip.a = triViewed.a
+		triClip.mat = triViewed.mat
+
+		// clip against the view frustum before dividing by w
+		clipFrustum(&triClip, r.guardBand, queue)
+
+		for queue.len() > 0 {
+			triProjected := *queue.pop()
+
+			triProjected.t.Scale(&triProjected)
+
+			triProjected.Scale()
+
+			// X/Y are inverted so put them back
+			triProjected.p[0].x *= -1.0
+			triProjected.p[1].x *= -1.0
+			triProjected.p[2].x *= -1.0
+			triProjected.p[0].y *= -1.0
+			triProjected.p[1].y *= -1.0
+			triProjected.p[2].y *= -1.0
+
+			offsetView := vec3d{
+				x: 1,
+				y: 1,
+				z: 0,
+				w: 1,
+			}
+
+			triProjected.p[0] = triProjected.p[0].Add(&offsetView)
+			triProjected.p[1] = triProjected.p[1].Add(&offsetView)
+			triProjected.p[2] = triProjected.p[2].Add(&offsetView)
+
+			triProjected.p[0].x *= 0.5 * float64(r.w)
+			triProjected.p[0].y *= 0.5 * float64(r.h)
+			triProjected.p[1].x *= 0.5 * float64(r.w)
+			triProjected.p[1].y *= 0.5 * float64(r.h)
+			triProjected.p[2].x *= 0.5 * float64(r.w)
+			triProjected.p[2].y *= 0.5 * float64(r.h)
+
+			out = append(out, triProjected)
+		}
+	}
+	return out
+}
+
+// rasterize fills the part of rt inside clip with the selected rasterizer.
+func (r *Renderer) rasterize(rt *rasterTriangle, clip image.Rectangle) {
+	t := &rt.t
+	if r.rasterizer == RasterizerEdge {
+		r.edgeTriangle(t, rt.tex, clip)
+		return
+	}
+
+	// drawTriangle(screen, &t)
+	// floor rather than truncate, corners in the guard band can be negative
+	r.texturedTriangle(
+		floor(t.p[0].x), floor(t.p[0].y), t.t[0].u, t.t[0].v,
+		floor(t.p[1].x), floor(t.p[1].y), t.t[1].u, t.t[1].v,
+		floor(t.p[2].x), floor(t.p[2].y), t.t[2].u, t.t[2].v,
+		t.t[0].w, t.t[1].w, t.t[2].w, rt.tex, clip)
+}
+
+func floor(f float64) int {
+	return int(math.Floor(f))
+}
+
+func TNormal(t *triangle) vec3d {
+	line1 := t.p[1].Sub(&t.p[0])
+	line2 := t.p[2].Sub(&t.p[0])
+	normal := line1.CrossProduct(&line2)
+	normal.Normalize()
+	return normal
+}
+
+// texturedTriangle fills the part of a projected triangle inside clip one
+// scanline at a time.
+func (r *Renderer) texturedTriangle(x1, y1 int, u1, v1 float64,
+	x2, y2 int, u2, v2 float64,
+	x3, y3 int, u3, v3 float64,
+	w1, w2, w3 float64, tex TextureAtlas, clip image.Rectangle) {
+
+	s := newSampler(tex)
+	g := triangleGradients(
+		float64(x1), float64(y1), u1, v1, w1,
+		float64(x2), float64(y2), u2, v2, w2,
+		float64(x3), float64(y3), u3, v3, w3)
+
+	if y2 < y1 {
+		y1, y2 = y2, y1
+		x1, x2 = x2, x1
+		u1, u2 = u2, u1
+		v1, v2 = v2, v1
+		w1, w2 = w2, w1
+	}
+
+	if y3 < y1 {
+		y1, y3 = y3, y1
+		x1, x3 = x3, x1
+		u1, u3 = u3, u1
+		v1, v3 = v3, v1
+		w1, w3 = w3, w1
+	}
+
+	if y3 < y2 {
+		y2, y3 = y3, y2
+		x2, x3 = x3, x2
+		u2, u3 = u3, u2
+		v2, v3 = v3, v2
+		w2, w3 = w3, w2
+	}
+
+	dy1 := y2 - y1
+	dx1 := x2 - x1
+	dv1 := v2 - v1
+	du1 := u2 - u1
+	dw1 := w2 - w1
+
+	dy2 := y3 - y1
+	dx2 := x3 - x1
+	dv2 := v3 - v1
+	du2 := u3 - u1
+	dw2 := w3 - w1
+
+	var dax_step = float64(0)
+	var dbx_step = float64(0)
+
+	var du1_step = float64(0)
+	var dv1_step = float64(0)
+	var dw1_step = float64(0)
+	var du2_step = float64(0)
+	var dv2_step = float64(0)
+	var dw2_step = float64(0)
+
+	if dy1 >= 0 {
+		dax_step = float64(dx1) / math.Abs(float64(dy1))
+	}
+
+	if dy2 >= 0 {
+		dbx_step = float64(dx2) / math.Abs(float64(dy2))
+	}
+
+	if dy1 >= 0 {
+		du1_step = float64(du1) / math.Abs(float64(dy1))
+	}
+	if dy1 >= 0 {
+		dv1_step = float64(dv1) / math.Abs(float64(dy1))
+	}
+	if dy1 >= 0 {
+		dw1_step = float64(dw1) / math.Abs(float64(dy1))
+	}
+
+	if dy2 >= 0 {
+		du2_step = float64(du2) / math.Abs(float64(dy2))
+	}
+	if dy2 >= 0 {
+		dv2_step = float64(dv2) / math.Abs(float64(dy2))
+	}
+	if dy2 >= 0 {
+		dw2_step = float64(dw2) / math.Abs(float64(dy2))
+	}
+
+	if dy1 >= 0 {
+		for i := max(y1, clip.Min.Y); i <= min(y2, clip.Max.Y-1); i++ {
+			ax := float64(x1) + float64(i-y1)*dax_step
+			bx := float64(x1) + float64(i-y1)*dbx_step
+
+			tex_su := u1 + float64(i-y1)*du1_step
+			tex_sv := v1 + float64(i-y1)*dv1_step
+			tex_sw := w1 + float64(i-y1)*dw1_step
+
+			tex_eu := u1 + float64(i-y1)*du2_step
+			tex_ev := v1 + float64(i-y1)*dv2_step
+			tex_ew := w1 + float64(i-y1)*dw2_step
+
+			if ax > bx {
+				ax, bx = bx, ax
+				tex_su, tex_eu = tex_eu, tex_su
+				tex_sv, tex_ev = tex_ev, tex_sv
+				tex_sw, tex_ew = tex_ew, tex_sw
+			}
+
+			tex_u := tex_su
+			tex_v := tex_sv
+			tex_w := tex_sw
+
+			tstep := 1.0 / (bx - ax)
+
+			// start at the first pixel inside the clip rectangle
+			k := math.Max(0, math.Ceil(float64(clip.Min.X)-ax))
+			end := math.Min(bx, float64(clip.Max.X))
+
+			for ; ax+k < end; k++ {
+				j := ax + k
+				t := k * tstep
+
+				tex_u = (1.0-t)*tex_su + t*tex_eu
+				tex_v = (1.0-t)*tex_sv + t*tex_ev
+				tex_w = (1.0-t)*tex_sw + t*tex_ew
+
+				if tex_w > r.depthBuffer[i*r.w+int(j)] {
+					o := (i*r.w + int(j)) * 4
+					s.sampleProjected(r.img.Pix[o:o+4], tex_u, tex_v, tex_w, &g)
+					r.depthBuffer[i*r.w+int(j)] = tex_w
+				}
+			}
+		}
+	}
+
+	dy1 = y3 - y2
+	dx1 = x3 - x2
+	dv1 = v3 - v2
+	du1 = u3 - u2
+	dw1 = w3 - w2
+
+	if dy1 >= 0 {
+		dax_step = float64(dx1) / math.Abs(float64(dy1))
+	}
+
+	if dy2 >= 0 {
+		dbx_step = float64(dx2) / math.Abs(float64(dy2))
+	}
+
+	du1_step = 0
+	dv1_step = 0
+
+	if dy1 >= 0 {
+		du1_step = du1 / math.Abs(float64(dy1))
+	}
+	if dy1 >= 0 {
+		dv1_step = dv1 / math.Abs(float64(dy1))
+	}
+	if dy1 >= 0 {
+		dw1_step = dw1 / math.Abs(float64(dy1))
+	}
+
+	if dy1 >= 0 {
+		for i := max(y2, clip.Min.Y); i <= min(y3, clip.Max.Y-1); i++ {
+			ax := float64(x2) + float64(i-y2)*dax_step
+			bx := float64(x1) + float64(i-y1)*dbx_step
+
+			tex_su := u2 + float64(i-y2)*du1_step
+			tex_sv := v2 + float64(i-y2)*dv1_step
+			tex_sw := w2 + float64(i-y2)*dw1_step
+
+			tex_eu := u1 + float64(i-y1)*du2_step
+			tex_ev := v1 + float64(i-y1)*dv2_step
+			tex_ew := w1 + float64(i-y1)*dw2_step
+
+			if ax > bx {
+				ax, bx = bx, ax
+				tex_su, tex_eu = tex_eu, tex_su
+				tex_sv, tex_ev = tex_ev, tex_sv
+				tex_sw, tex_ew = tex_ew, tex_sw
+			}
+
+			tex_u := tex_su
+			tex_v := tex_sv
+			tex_w := tex_sw
+
+			tstep := 1.0 / (bx - ax)
+
+			// start at the first pixel inside the clip rectangle
+			k := math.Max(0, math.Ceil(float64(clip.Min.X)-ax))
+			end := math.Min(bx, float64(clip.Max.X))
+
+			for ; ax+k < end; k++ {
+				j := ax + k
+				t := k * tstep
+
+				tex_u = (1.0-t)*tex_su + t*tex_eu
+				tex_v = (1.0-t)*tex_sv + t*tex_ev
+				tex_w = (1.0-t)*tex_sw + t*tex_ew
+
+				// Draw(j, i, tex->SampleGlyph(tex_u / tex_w, tex_v / tex_w), tex->SampleColour(tex_u / tex_w, tex_v / tex_w));
+				if tex_w > r.depthBuffer[i*r.w+int(j)] {
+					o := (i*r.w + int(j)) * 4
+					s.sampleProjected(r.img.Pix[o:o+4], tex_u, tex_v, tex_w, &g)
+					r.depthBuffer[i*r.w+int(j)] = tex_w
+				}
+			}
+		}
+	}
+}
+
+// triangleGradients returns how the perspective divided texture coordinates
+// change per pixel across the plane of a triangle.
+func triangleGradients(x1, y1, u1, v1, w1,
+	x2, y2, u2, v2, w2,
+	x3, y3, u3, v3, w3 float64) uvGradients {
+
+	dx1, dy1 := x2-x1, y2-y1
+	dx2, dy2 := x3-x1, y3-y1
+	area := dx1*dy2 - dx2*dy1
+	if area == 0 {
+		return uvGradients{}
+	}
+
+	ddx := func(a1, a2, a3 float64) float64 {
+		return ((a2-a1)*dy2 - (a3-a1)*dy1) / area
+	}
+	ddy := func(a1, a2, a3 float64) float64 {
+		return ((a3-a1)*dx1 - (a2-a1)*dx2) / area
+	}
+
+	return uvGradients{
+		dudx: ddx(u1, u2, u3),
+		dvdx: ddx(v1, v2, v3),
+		dwdx: ddx(w1, w2, w3),
+		dudy: ddy(u1, u2, u3),
+		dvdy: ddy(v1, v2, v3),
+		dwdy: ddy(w1, w2, w3),
+	}
+}
@@ -0,0 +1,367 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "regenerate the golden images in testdata/golden")
+
+const (
+	// pixelTolerance is the largest per-channel difference still treated as
+	// a matching pixel.
+	pixelTolerance = 2
+	// maxBadPixels is the fraction of pixels allowed to exceed
+	// pixelTolerance, to absorb floating point differences between platforms
+	// along triangle edges.
+	maxBadPixels = 0.002
+)
+
+type goldenCase struct {
+	name string
+	opts renderOptions
+	// uv generates the texture coordinates of models without any, so their
+	// images show the texture across the faces instead of a single texel
+	uv func(p vec3d) (u, v float64)
+}
+
+var goldenCases = []goldenCase{
+	{"cube3", renderOptions{objFile: "cube3.obj", texFile: "colors.png", vCamera: vec3d{2, 2, -4, 1}, fYaw: 0.45}, nil},
+	{"cube2", renderOptions{objFile: "cube2.obj", vCamera: vec3d{2, 2, -4, 1}, fYaw: 0.45}, nil},
+	{"cube2_bilinear", renderOptions{objFile: "cube2.obj", vCamera: vec3d{1.2, 1.2, -2.2, 1}, fYaw: 0.45, filter: FilterBilinear}, nil},
+	{"cube2_edge", renderOptions{objFile: "cube2.obj", vCamera: vec3d{2, 2, -4, 1}, fYaw: 0.45, rasterizer: RasterizerEdge}, nil},
+	{"level1_trilinear", renderOptions{objFile: "Level1.obj", texFile: "High.png", vCamera: vec3d{0, 8, -40, 1}, filter: FilterBilinear, mipmap: MipmapLinear}, nil},
+	{"teapot", renderOptions{objFile: "teapot.obj", vCamera: vec3d{1, 2, -7, 1}, fYaw: 0.15}, planarUV(0, 1, -3, 0, 6.5, 3.2)},
+	{"ship", renderOptions{objFile: "ship.obj", vCamera: vec3d{3, 3, -10, 1}, fYaw: 0.3}, planarUV(0, 1, -4.5, -1, 9, 2)},
+	{"mountains", renderOptions{objFile: "mountains.obj", vCamera: vec3d{0, 45, -120, 1}}, planarUV(0, 2, -80, -80, 160, 160)},
+}
+
+// planarUV projects positions along the remaining axis onto the plane of
+// the axes i and j, mapping the rectangle of the given corner and size to
+// the unit square.
+func planarUV(i, j int, u0, v0, du, dv float64) func(p vec3d) (u, v float64) {
+	return func(p vec3d) (u, v float64) {
+		axes := [3]float64{p.x, p.y, p.z}
+		return (axes[i] - u0) / du, (axes[j] - v0) / dv
+	}
+}
+
+// render renders the case with opts, which the tests derive from c.opts.
+func (c *goldenCase) render(opts renderOptions) (*image.RGBA, error) {
+	m, err := loadScene(opts)
+	if err != nil {
+		return nil, err
+	}
+	if c.uv != nil {
+		for i := range m.vertices {
+			v := &m.vertices[i]
+			v.t.u, v.t.v = c.uv(v.p)
+		}
+	}
+	return renderScene(m, opts), nil
+}
+
+func TestGolden(t *testing.T) {
+	for _, c := range goldenCases {
+		t.Run(c.name, func(t *testing.T) {
+			opts := c.opts
+			opts.fFov = 90
+			opts.width = 256
+			opts.height = 256
+
+			got, err := c.render(opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			goldenFile := filepath.Join("testdata", "golden", c.name+".png")
+			if *update {
+				if err := writePNG(goldenFile, got); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := readPNG(goldenFile)
+			if err != nil {
+				t.Fatalf("%v (run with -update to create it)", err)
+			}
+
+			diff, bad := compareImages(got, want)
+			if bad < 0 {
+				t.Fatalf("size mismatch: got %v, want %v", got.Bounds(), want.Bounds())
+			}
+			total := got.Bounds().Dx() * got.Bounds().Dy()
+			if float64(bad) > maxBadPixels*float64(total) {
+				failedDir := filepath.Join("testdata", "failed")
+				if err := writePNG(filepath.Join(failedDir, c.name+".png"), got); err != nil {
+					t.Error(err)
+				}
+				if err := writePNG(filepath.Join(failedDir, c.name+"_diff.png"), diff); err != nil {
+					t.Error(err)
+				}
+				t.Errorf("%d of %d pixels differ from %s, see %s", bad, total, goldenFile, failedDir)
+			}
+		})
+	}
+}
+
+func TestTiledMatchesSerial(t *testing.T) {
+	for _, c := range goldenCases {
+		t.Run(c.name, func(t *testing.T) {
+			opts := c.opts
+			opts.fFov = 90
+			// not a multiple of tileSize, so the border tiles are partial
+			opts.width = 300
+			opts.height = 200
+
+			opts.workers = 1
+			serial, err := c.render(opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts.workers = 4
+			tiled, err := c.render(opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(serial.Pix, tiled.Pix) {
+				_, bad := compareImages(tiled, serial)
+				t.Errorf("%d pixels differ between serial and tiled rasterization", bad)
+			}
+		})
+	}
+}
+
+func TestGuardBandMatchesClipping(t *testing.T) {
+	for _, c := range goldenCases {
+		t.Run(c.name, func(t *testing.T) {
+			opts := c.opts
+			opts.fFov = 90
+			opts.width = 256
+			opts.height = 256
+			// the scanline rasterizer snaps corners to whole pixels, which
+			// moves the edges of triangles reaching far off screen
+			opts.rasterizer = RasterizerEdge
+
+			clipped, err := c.render(opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts.guardBand = defaultGuardBand
+			scissored, err := c.render(opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, bad := compareImages(scissored, clipped); float64(bad) > maxBadPixels*256*256 {
+				t.Errorf("%d pixels differ between clipping and scissoring", bad)
+			}
+		})
+	}
+}
+
+func TestLoadSceneFallbackTexture(t *testing.T) {
+	tests := []struct {
+		objFile string
+		want    bool
+	}{
+		// the quad has no materials
+		{"", true},
+		{"teapot.obj", true},
+		// every face of cube2 has a material
+		{"cube2.obj", false},
+	}
+
+	for _, test := range tests {
+		m, err := loadScene(renderOptions{objFile: test.objFile})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := m.tex != nil; got != test.want {
+			t.Errorf("%q: got fallback texture %v, want %v", test.objFile, got, test.want)
+		}
+	}
+}
+
+func TestSetGuardBandClamps(t *testing.T) {
+	r := NewRenderer(1024, 512, defaultProjection(1024, 512, 90))
+	tests := []struct {
+		guardBand, want float64
+	}{
+		{0.5, 1},
+		{math.NaN(), 1},
+		{defaultGuardBand, defaultGuardBand},
+		{1e5, maxGuardBand(1024, 512)},
+		{math.Inf(1), maxGuardBand(1024, 512)},
+	}
+
+	for _, test := range tests {
+		r.SetGuardBand(test.guardBand)
+		if got := r.GuardBand(); got != test.want {
+			t.Errorf("SetGuardBand(%g): got %g, want %g", test.guardBand, got, test.want)
+		}
+	}
+}
+
+// compareImages returns an image highlighting the pixels of got that differ
+// from want by more than pixelTolerance in any channel, and the number of
+// those pixels. It returns -1 if the images are not the same size.
+func compareImages(got *image.RGBA, want image.Image) (*image.RGBA, int) {
+	if got.Bounds().Size() != want.Bounds().Size() {
+		return nil, -1
+	}
+
+	b := got.Bounds()
+	diff := image.NewRGBA(b)
+	bad := 0
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			g := got.RGBAAt(x, y)
+			w := color.RGBAModel.Convert(want.At(x, y)).(color.RGBA)
+
+			if channelDiff(g.R, w.R) > pixelTolerance ||
+				channelDiff(g.G, w.G) > pixelTolerance ||
+				channelDiff(g.B, w.B) > pixelTolerance ||
+				channelDiff(g.A, w.A) > pixelTolerance {
+				diff.SetRGBA(x, y, color.RGBA{R: 255, A: 255})
+				bad++
+			} else {
+				// keep a faint copy of the image for orientation
+				diff.SetRGBA(x, y, color.RGBA{R: g.R / 4, G: g.G / 4, B: g.B / 4, A: 255})
+			}
+		}
+	}
+	return diff, bad
+}
+
+func channelDiff(a, b uint8) int {
+	if a > b {
+		return int(a - b)
+	}
+	return int(b - a)
+}
+
+func readPNG(filename string) (image.Image, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	img, err := png.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", filename, err)
+	}
+	return img, nil
+}
+
+// benchmarkRender renders the frame described by opts b.N times.
+func benchmarkRender(b *testing.B, opts renderOptions) {
+	textures := NewTextureLoader(nil)
+	m := mesh{}
+	if err := m.Load(opts.objFile, textures); err != nil {
+		b.Fatal(err)
+	}
+	tex, err := textures.Load(opts.texFile)
+	if err != nil {
+		b.Fatal(err)
+	}
+	m.tex = tex
+
+	vLookDirection := lookDirection(opts.fYaw)
+	matView := matrixMakeView(&opts.vCamera, &vLookDirection)
+	matWorld := matrixMakeIdentity()
+	renderer := NewRenderer(opts.width, opts.height, defaultProjection(opts.width, opts.height, 90))
+	renderer.SetWorkers(opts.workers)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		renderer.Render(&m, &matWorld, &matView, &opts.vCamera)
+	}
+}
+
+func BenchmarkRenderTeapot(b *testing.B) {
+	benchmarkRender(b, renderOptions{
+		objFile: "teapot.obj",
+		texFile: "wall.png",
+		vCamera: vec3d{1, 2, -7, 1},
+		fYaw:    0.15,
+		width:   512,
+		height:  512,
+		workers: 1,
+	})
+}
+
+func BenchmarkRenderLevel1(b *testing.B) {
+	for _, workers := range []int{1, 0} {
+		name := "serial"
+		if workers == 0 {
+			name = "tiled"
+		}
+		b.Run(name, func(b *testing.B) {
+			benchmarkRender(b, renderOptions{
+				objFile: "Level1.obj",
+				texFile: "High.png",
+				vCamera: vec3d{0, 8, -40, 1},
+				width:   1024,
+				height:  1024,
+				workers: workers,
+			})
+		})
+	}
+}
+
+// BenchmarkRenderAllocs renders the teapot with every rasterizer and worker
+// setup and fails if a frame allocates once the renderer's buffers have
+// grown to their steady state size.
+func BenchmarkRenderAllocs(b *testing.B) {
+	textures := NewTextureLoader(nil)
+	m := mesh{}
+	if err := m.Load("teapot.obj", textures); err != nil {
+		b.Fatal(err)
+	}
+	tex, err := textures.Load("wall.png")
+	if err != nil {
+		b.Fatal(err)
+	}
+	m.tex = tex
+
+	vCamera := vec3d{1, 2, -7, 1}
+	vLookDirection := lookDirection(0.15)
+	matView := matrixMakeView(&vCamera, &vLookDirection)
+	matWorld := matrixMakeIdentity()
+
+	for _, rasterizer := range []Rasterizer{RasterizerScanline, RasterizerEdge} {
+		for _, workers := range []int{1, 4} {
+			b.Run(fmt.Sprintf("%s/workers=%d", rasterizer, workers), func(b *testing.B) {
+				renderer := NewRenderer(256, 256, defaultProjection(256, 256, 90))
+				renderer.SetRasterizer(rasterizer)
+				renderer.SetWorkers(workers)
+				render := func() {
+					renderer.Render(&m, &matWorld, &matView, &vCamera)
+				}
+				// the first frame grows the buffers
+				render()
+				if allocs := testing.AllocsPerRun(10, render); allocs != 0 {
+					b.Fatalf("got %v allocations per frame, want 0", allocs)
+				}
+
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					render()
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// frame is the input of one frame: the actions and the seconds the frame
+// took. Replaying the frames of a recording from the same starting state
+// moves the camera along the same path.
+type frame struct {
+	dt      float64
+	actions actions
+}
+
+const replayHeader = "# 3DGo input recording, one frame per line"
+
+// recorder writes frames as text, one line per frame below a line naming
+// the columns. Numbers are written in their shortest exact form, so they
+// read back bit for bit.
+type recorder struct {
+	w   *bufio.Writer
+	buf []byte
+}
+
+func newRecorder(w io.Writer) (*recorder, error) {
+	r := &recorder{w: bufio.NewWriter(w)}
+
+	columns := []string{"dt"}
+	for _, name := range actionNames {
+		columns = append(columns, name)
+	}
+	if _, err := fmt.Fprintf(r.w, "%s\n%s\n", replayHeader, strings.Join(columns, " ")); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *recorder) record(f *frame) error {
+	r.buf = strconv.AppendFloat(r.buf[:0], f.dt, 'g', -1, 64)
+	for _, v := range f.actions {
+		r.buf = append(r.buf, ' ')
+		r.buf = strconv.AppendFloat(r.buf, v, 'g', -1, 64)
+	}
+	r.buf = append(r.buf, '\n')
+
+	_, err := r.w.Write(r.buf)
+	return err
+}
+
+// flush writes buffered frames to the underlying writer.
+func (r *recorder) flush() error {
+	return r.w.Flush()
+}
+
+// readRecording reads the frames written by a recorder. Columns are matched
+// to actions by name, actions missing from the recording stay 0. Errors are
+// reported as *ParseError.
+func readRecording(r io.Reader, filename string) ([]frame, error) {
+	var frames []frame
+	// columns holds the action of every column after dt
+	var columns []action
+
+	err := scanLines(r, filename, func(_ int, fields []string) error {
+		if columns == nil {
+			if fields[0] != "dt" {
+				return errors.New("first column must be dt")
+			}
+			columns = make([]action, 0, len(fields)-1)
+			for _, name := range fields[1:] {
+				a, err := parseAction(name)
+				if err != nil {
+					return err
+				}
+				columns = append(columns, a)
+			}
+			return nil
+		}
+
+		if len(fields) != len(columns)+1 {
+			return fmt.Errorf("frame needs %d columns, got %d", len(columns)+1, len(fields))
+		}
+		values, err := parseFloats(fields)
+		if err != nil {
+			return err
+		}
+		f := frame{dt: values[0]}
+		for i, a := range columns {
+			f.actions[a] = values[i+1]
+		}
+		frames = append(frames, f)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if columns == nil {
+		return nil, &ParseError{Filename: filename, Err: errors.New("missing column names")}
+	}
+
+	return frames, nil
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testFrames are a session of frames with awkward delta times and mouse
+// motion, which ends dragging the orbit camera.
+func testFrames() []frame {
+	var frames []frame
+	for i := 0; i < 100; i++ {
+		f := frame{dt: 1.0/60 + float64(i%7)*1e-3/3}
+		f.actions[actionMoveForward] = 1
+		f.actions[actionMoveRight] = float64(i%3 - 1)
+		f.actions[actionYaw] = math.Sin(float64(i))
+		f.actions[actionLookX] = float64(i%3) * 0.5
+		f.actions[actionLookY] = float64(i%5) * 0.1
+		switch {
+		case i == 10:
+			f.actions[actionCaptureCursor] = 1
+		case i == 50:
+			f.actions[actionToggleRasterizer] = 1
+		case i == 70:
+			f.actions[actionToggleOrbit] = 1
+		case i > 80:
+			f.actions[actionOrbit] = 1
+		}
+		frames = append(frames, f)
+	}
+	return frames
+}
+
+// fakeCursor remembers whether the simulation captured the cursor.
+type fakeCursor struct {
+	captured bool
+}
+
+func (c *fakeCursor) setCaptured(captured bool) {
+	c.captured = captured
+}
+
+// newTestSimulation returns a simulation of the cube, seen from where the
+// window starts, that steps by the time of c.
+func newTestSimulation(c clock) *simulation {
+	s := &simulation{
+		renderer:            NewRenderer(64, 64, defaultProjection(64, 64, 90)),
+		cursor:              &fakeCursor{},
+		timestep:            newFixedStep(c, simulationStep),
+		orientation:         quatIdentity(),
+		previousOrientation: quatIdentity(),
+		camera:              newCamera(vec3d{0.5, 0.5, 4.5, 1}, 0),
+	}
+	s.mesh.LoadCube()
+	s.previous = s.camera
+	return s
+}
+
+// simulationState is the part of a simulation that replaying must repeat.
+type simulationState struct {
+	camera     camera
+	orbit      orbitCamera
+	orbiting   bool
+	rasterizer Rasterizer
+	captured   bool
+}
+
+func (s *simulation) state() simulationState {
+	return simulationState{
+		camera:     s.camera,
+		orbit:      s.orbit,
+		orbiting:   s.orbiting,
+		rasterizer: s.renderer.Rasterizer(),
+		captured:   s.cursor.(*fakeCursor).captured,
+	}
+}
+
+func TestRecordingRoundTrip(t *testing.T) {
+	frames := testFrames()
+
+	var buf bytes.Buffer
+	r, err := newRecorder(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range frames {
+		if err := r.record(&frames[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	replayed, err := readRecording(&buf, "test.rec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replayed) != len(frames) {
+		t.Fatalf("got %d frames, want %d", len(replayed), len(frames))
+	}
+	for i := range frames {
+		if replayed[i] != frames[i] {
+			t.Fatalf("frame %d: got %v, want %v", i, replayed[i], frames[i])
+		}
+	}
+
+}
+
+func TestReplayFollowsSession(t *testing.T) {
+	frames := testFrames()
+
+	// play the session live through the fixed timestep, recording it
+	var buf bytes.Buffer
+	c := &fakeClock{}
+	live := newTestSimulation(c)
+	var err error
+	if live.recorder, err = newRecorder(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var want []simulationState
+	for i := range frames {
+		c.t += time.Duration(frames[i].dt * float64(time.Second))
+		if err := live.update(&frames[i].actions); err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, live.state())
+	}
+	if err := live.recorder.flush(); err != nil {
+		t.Fatal(err)
+	}
+	if !want[len(want)-1].orbiting {
+		t.Fatal("the session does not end orbiting")
+	}
+
+	// replaying the recording at the same frame times, without any input,
+	// must follow exactly the same path
+	c = &fakeClock{}
+	replay := newTestSimulation(c)
+	if replay.replay, err = readRecording(&buf, "test.rec"); err != nil {
+		t.Fatal(err)
+	}
+	for i := range frames {
+		c.t += time.Duration(frames[i].dt * float64(time.Second))
+		if err := replay.update(&actions{}); err != nil {
+			t.Fatal(err)
+		}
+		if got := replay.state(); got != want[i] {
+			t.Fatalf("frame %d: replayed %+v, want %+v", i, got, want[i])
+		}
+	}
+	if len(replay.replay) != 0 {
+		t.Errorf("%d recorded steps were not replayed", len(replay.replay))
+	}
+}
+
+func TestReadRecordingColumns(t *testing.T) {
+	frames, err := readRecording(strings.NewReader(replayHeader+`
+dt Yaw MoveForward
+0.5 1 -1
+
+0.25 0 2
+`), "test.rec")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []frame{
+		{dt: 0.5, actions: actions{actionYaw: 1, actionMoveForward: -1}},
+		{dt: 0.25, actions: actions{actionMoveForward: 2}},
+	}
+	if len(frames) != len(want) {
+		t.Fatalf("got %d frames, want %d", len(frames), len(want))
+	}
+	for i := range want {
+		if frames[i] != want[i] {
+			t.Errorf("frame %d: got %v, want %v", i, frames[i], want[i])
+		}
+	}
+}
+
+func TestReadRecordingErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		line  int
+	}{
+		{"", 0},
+		{"Yaw dt\n1 2", 1},
+		{"dt Jump\n1 2", 1},
+		{"dt Yaw\n0.1 1\n0.1", 3},
+		{"dt Yaw\n0.1 left", 2},
+	}
+
+	for _, test := range tests {
+		_, err := readRecording(strings.NewReader(test.input), "test.rec")
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) {
+			t.Errorf("%q: got %v, want a *ParseError", test.input, err)
+			continue
+		}
+		if parseErr.Line != test.line {
+			t.Errorf("%q: error on line %d, want %d", test.input, parseErr.Line, test.line)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+// cursor captures the mouse cursor for free look and releases it again.
+// Tests use a fake instead of ebiten's cursor.
+type cursor interface {
+	setCaptured(captured bool)
+}
+
+// simulation is the state of the game that the input changes. It advances
+// in fixed steps and follows only from the frames it steps through, so
+// replaying recorded frames repeats a session.
+type simulation struct {
+	mesh     mesh
+	renderer *Renderer
+	cursor   cursor
+	// timestep runs the simulation at a fixed rate, pending collects the
+	// input until the next step
+	timestep    *fixedStep
+	pending     actions
+	elapsedTime float64
+	fTheta      float64
+	camera      camera
+	// orbit replaces camera while orbiting is set
+	orbit    orbitCamera
+	orbiting bool
+	// orientation rotates the mesh
+	orientation quat
+	// previous and previousOrientation are the camera and mesh of the step
+	// before, Draw interpolates between them and the current ones
+	previous            camera
+	previousOrientation quat
+	// recorder records every frame if set, replay holds the frames still to
+	// be replayed instead of live input
+	recorder *recorder
+	replay   []frame
+}
+
+// update collects the actions of one frame and runs the steps due since the
+// last frame.
+func (s *simulation) update(a *actions) error {
+	s.pending.accumulate(a)
+
+	for steps := s.timestep.advance(); steps > 0; steps-- {
+		f := frame{dt: s.timestep.seconds(), actions: s.pending.take()}
+		if len(s.replay) > 0 {
+			f = s.replay[0]
+			s.replay = s.replay[1:]
+		}
+		if s.recorder != nil {
+			if err := s.recorder.record(&f); err != nil {
+				return err
+			}
+		}
+
+		s.step(&f)
+	}
+	return nil
+}
+
+// step advances the simulation by one frame.
+func (s *simulation) step(f *frame) {
+	a := &f.actions
+	s.previous = s.view()
+	s.previousOrientation = s.orientation
+	s.elapsedTime += f.dt * 1000
+
+	// s.mesh.translateZ(0.01)
+	s.fTheta = 1.0 * (s.elapsedTime / 1000)
+
+	// s.orientation = quatFromEuler(s.fTheta, s.fTheta, s.fTheta)
+
+	if a.triggered(actionToggleRasterizer) {
+		if s.renderer.Rasterizer() == RasterizerEdge {
+			s.renderer.SetRasterizer(RasterizerScanline)
+		} else {
+			s.renderer.SetRasterizer(RasterizerEdge)
+		}
+	}
+
+	if a.triggered(actionToggleGuardBand) {
+		if s.renderer.GuardBand() > 1 {
+			s.renderer.SetGuardBand(1)
+		} else {
+			s.renderer.SetGuardBand(defaultGuardBand)
+		}
+	}
+
+	if a.triggered(actionToggleOrbit) {
+		s.orbiting = !s.orbiting
+		if s.orbiting && s.orbit.distance == 0 {
+			s.fitOrbit()
+		}
+		if !s.orbiting {
+			s.camera = s.orbit.camera()
+		}
+		s.cursor.setCaptured(false)
+	}
+
+	if s.orbiting {
+		if a.triggered(actionFit) {
+			s.fitOrbit()
+		}
+		s.orbit.update(a)
+		return
+	}
+
+	if a.triggered(actionCaptureCursor) {
+		s.cursor.setCaptured(true)
+	}
+	if a.triggered(actionReleaseCursor) {
+		s.cursor.setCaptured(false)
+	}
+
+	s.camera.update(a, f.dt)
+}
+
+// fitOrbit aims the orbit camera at the mesh and backs it off until the
+// whole mesh is in view.
+func (s *simulation) fitOrbit() {
+	center, radius := s.mesh.boundingSphere()
+	matWorld := worldMatrix(&s.orientation)
+	center = matWorld.matrixMultiplyVector(&center)
+	s.orbit.fit(center, radius, &s.renderer.matProj)
+}
+
+// view returns the pose of the active camera.
+func (s *simulation) view() camera {
+	if s.orbiting {
+		return s.orbit.camera()
+	}
+	return s.camera
+}
+
+// worldMatrix places the mesh in front of the camera's starting position.
+func worldMatrix(orientation *quat) mat4x4 {
+	rotation := orientation.Matrix()
+	return matrixMakeTRS(vec3d{0, 0, 5, 1}, &rotation, vec3d{1, 1, 1, 1})
+}
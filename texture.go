@@ -0,0 +1,492 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+)
+
+var (
+	//go:embed ryu.png
+	textureData []byte
+)
+
+// TextureFilter selects how texels are combined when a texture is sampled.
+type TextureFilter int
+
+const (
+	// FilterNearest uses the texel the sample falls into.
+	FilterNearest TextureFilter = iota
+	// FilterBilinear blends the four texels around the sample.
+	FilterBilinear
+)
+
+// AddressMode selects how texture coordinates outside of [0, 1] are mapped
+// back onto the texture.
+type AddressMode int
+
+const (
+	// AddressRepeat tiles the texture.
+	AddressRepeat AddressMode = iota
+	// AddressMirroredRepeat tiles the texture, flipping every other tile.
+	AddressMirroredRepeat
+	// AddressClampToEdge repeats the outermost texels.
+	AddressClampToEdge
+)
+
+// MipmapFilter selects whether and how mipmap levels are used when a texture
+// is minified.
+type MipmapFilter int
+
+const (
+	// MipmapNone always samples the full resolution texture.
+	MipmapNone MipmapFilter = iota
+	// MipmapNearest samples the level closest to the pixel's footprint.
+	MipmapNearest
+	// MipmapLinear blends the two closest levels, which together with
+	// FilterBilinear gives trilinear filtering.
+	MipmapLinear
+)
+
+type TextureAtlas interface {
+	W() int
+	H() int
+	ColorAt(x, y int) color.Color
+	// Pix returns the texture as tightly packed, premultiplied RGBA rows of
+	// W() pixels, for the rasterizer to index directly.
+	Pix() []uint8
+	Filter() TextureFilter
+	AddressMode() AddressMode
+	MipmapFilter() MipmapFilter
+	// MipLevels returns the length of the mipmap chain, level 0 being the
+	// texture itself.
+	MipLevels() int
+	// MipLevel returns the pixels of a mipmap level laid out like Pix.
+	MipLevel(level int) (pix []uint8, w, h int)
+}
+
+type TextureAtlasImpl struct {
+	w, h    int
+	pix     []uint8
+	mipmaps []mipLevel
+	filter  TextureFilter
+	address AddressMode
+	mipmap  MipmapFilter
+}
+
+func (t *TextureAtlasImpl) W() int {
+	return t.w
+}
+
+func (t *TextureAtlasImpl) H() int {
+	return t.h
+}
+
+func (t *TextureAtlasImpl) ColorAt(x, y int) color.Color {
+	if x < 0 || y < 0 || x >= t.w || y >= t.h {
+		return color.RGBA{}
+	}
+	o := (y*t.w + x) * 4
+	return color.RGBA{t.pix[o], t.pix[o+1], t.pix[o+2], t.pix[o+3]}
+}
+
+func (t *TextureAtlasImpl) Pix() []uint8 {
+	return t.pix
+}
+
+func (t *TextureAtlasImpl) Filter() TextureFilter {
+	return t.filter
+}
+
+func (t *TextureAtlasImpl) SetFilter(filter TextureFilter) {
+	t.filter = filter
+}
+
+func (t *TextureAtlasImpl) AddressMode() AddressMode {
+	return t.address
+}
+
+func (t *TextureAtlasImpl) SetAddressMode(address AddressMode) {
+	t.address = address
+}
+
+func (t *TextureAtlasImpl) MipmapFilter() MipmapFilter {
+	return t.mipmap
+}
+
+func (t *TextureAtlasImpl) SetMipmapFilter(mipmap MipmapFilter) {
+	t.mipmap = mipmap
+}
+
+func (t *TextureAtlasImpl) MipLevels() int {
+	return len(t.mipmaps)
+}
+
+func (t *TextureAtlasImpl) mipLevels() []mipLevel {
+	return t.mipmaps
+}
+
+func (t *TextureAtlasImpl) MipLevel(level int) ([]uint8, int, int) {
+	l := t.mipmaps[level]
+	return l.pix, l.w, l.h
+}
+
+// LoadTexture decodes the texture embedded into the binary.
+func (t *TextureAtlasImpl) LoadTexture() error {
+	texture, err := decodeTexture(bytes.NewReader(textureData))
+	if err != nil {
+		return fmt.Errorf("decoding embedded texture: %w", err)
+	}
+	*t = *texture
+	return nil
+}
+
+// decodeTexture decodes a PNG, JPEG or GIF image into RGBA pixels and
+// generates its mipmaps.
+func decodeTexture(r io.Reader) (*TextureAtlasImpl, error) {
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return nil, err
+	}
+	return newTexture(img), nil
+}
+
+func newTexture(img image.Image) *TextureAtlasImpl {
+	b := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+
+	return &TextureAtlasImpl{
+		w:       b.Dx(),
+		h:       b.Dy(),
+		pix:     rgba.Pix,
+		mipmaps: generateMipmaps(rgba.Pix, b.Dx(), b.Dy()),
+	}
+}
+
+// TextureLoader loads textures from the file system and caches them by path
+// and sampling settings, so materials and meshes referring to the same image
+// share one texture. Models and material libraries are read through it too.
+// It is safe for concurrent use.
+type TextureLoader struct {
+	fsys fs.FS
+
+	// Filter, Address and Mipmap are applied to textures as they are
+	// loaded.
+	Filter  TextureFilter
+	Address AddressMode
+	Mipmap  MipmapFilter
+
+	mu    sync.Mutex
+	cache map[textureKey]TextureAtlas
+}
+
+// textureKey identifies a loaded texture. Changing the loader's settings
+// loads the image again instead of returning one set up differently.
+type textureKey struct {
+	name    string
+	filter  TextureFilter
+	address AddressMode
+	mipmap  MipmapFilter
+}
+
+// NewTextureLoader returns a loader reading from fsys. With a nil fsys
+// textures are read from the operating system's file system.
+func NewTextureLoader(fsys fs.FS) *TextureLoader {
+	return &TextureLoader{
+		fsys:  fsys,
+		cache: map[textureKey]TextureAtlas{},
+	}
+}
+
+// Load returns the texture stored at name, decoding it on first use.
+func (l *TextureLoader) Load(name string) (TextureAtlas, error) {
+	name = l.clean(name)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key := textureKey{name: name, filter: l.Filter, address: l.Address, mipmap: l.Mipmap}
+	if tex, ok := l.cache[key]; ok {
+		return tex, nil
+	}
+
+	file, err := l.open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	tex, err := decodeTexture(file)
+	if err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", name, err)
+	}
+	tex.SetFilter(l.Filter)
+	tex.SetAddressMode(l.Address)
+	tex.SetMipmapFilter(l.Mipmap)
+	l.cache[key] = tex
+	return tex, nil
+}
+
+func (l *TextureLoader) clean(name string) string {
+	if l.fsys != nil {
+		return path.Clean(filepath.ToSlash(name))
+	}
+	return filepath.Clean(name)
+}
+
+// openFile opens the file name from the loader's file system.
+func (l *TextureLoader) openFile(name string) (io.ReadCloser, error) {
+	return l.open(l.clean(name))
+}
+
+func (l *TextureLoader) open(name string) (io.ReadCloser, error) {
+	if l.fsys != nil {
+		return l.fsys.Open(name)
+	}
+	return os.Open(name)
+}
+
+// sampler reads filtered texels from a texture's pixels. It is set up once
+// per triangle so the rasterizer does not go through the TextureAtlas
+// interface for every pixel.
+type sampler struct {
+	levels  []mipLevel
+	filter  TextureFilter
+	address AddressMode
+	mipmap  MipmapFilter
+}
+
+// mipLevel is one level of a texture's mipmap chain.
+type mipLevel struct {
+	pix  []uint8
+	w, h int
+}
+
+// mipChain is implemented by textures that keep their mipmap chain as a
+// slice, which samplers share instead of copying it level by level.
+type mipChain interface {
+	mipLevels() []mipLevel
+}
+
+func newSampler(tex TextureAtlas) sampler {
+	s := sampler{
+		filter:  tex.Filter(),
+		address: tex.AddressMode(),
+		mipmap:  tex.MipmapFilter(),
+	}
+
+	levels := 1
+	if s.mipmap != MipmapNone {
+		levels = tex.MipLevels()
+	}
+	if c, ok := tex.(mipChain); ok {
+		s.levels = c.mipLevels()[:levels]
+		return s
+	}
+	s.levels = make([]mipLevel, levels)
+	for i := range s.levels {
+		l := &s.levels[i]
+		l.pix, l.w, l.h = tex.MipLevel(i)
+	}
+	return s
+}
+
+// uvGradients holds the screen space derivatives of the perspective divided
+// texture coordinates u/w, v/w and 1/w across a triangle.
+type uvGradients struct {
+	dudx, dvdx, dwdx float64
+	dudy, dvdy, dwdy float64
+}
+
+// sampleProjected writes the color at the perspective divided texture
+// coordinate u/w, v/w, 1/w into dst. With mipmapping enabled the level is
+// chosen from the coordinate's screen space footprint, computed from g.
+func (s *sampler) sampleProjected(dst []uint8, u, v, w float64, g *uvGradients) {
+	tu := u / w
+	tv := v / w
+
+	if s.mipmap == MipmapNone {
+		s.sample(dst, &s.levels[0], tu, tv)
+		return
+	}
+
+	// derivatives of u/w by the quotient rule, in texels of the base level
+	base := &s.levels[0]
+	dudx := (g.dudx - tu*g.dwdx) / w * float64(base.w)
+	dvdx := (g.dvdx - tv*g.dwdx) / w * float64(base.h)
+	dudy := (g.dudy - tu*g.dwdy) / w * float64(base.w)
+	dvdy := (g.dvdy - tv*g.dwdy) / w * float64(base.h)
+	rho := math.Max(dudx*dudx+dvdx*dvdx, dudy*dudy+dvdy*dvdy)
+	lod := 0.5 * math.Log2(rho)
+
+	s.sampleLod(dst, tu, tv, lod)
+}
+
+// sampleLod writes the color at texture coordinate u, v into dst, reading
+// from the mipmap level of detail lod.
+func (s *sampler) sampleLod(dst []uint8, u, v, lod float64) {
+	maxLevel := float64(len(s.levels) - 1)
+	if !(lod > 0) {
+		lod = 0
+	}
+	if lod > maxLevel {
+		lod = maxLevel
+	}
+
+	if s.mipmap == MipmapNearest {
+		s.sample(dst, &s.levels[int(lod+0.5)], u, v)
+		return
+	}
+
+	level := int(lod)
+	s.sample(dst, &s.levels[level], u, v)
+	if level == len(s.levels)-1 {
+		return
+	}
+
+	a := lod - float64(level)
+	var next [4]uint8
+	s.sample(next[:], &s.levels[level+1], u, v)
+	for c := 0; c < 4; c++ {
+		dst[c] = uint8(float64(dst[c])*(1-a) + float64(next[c])*a + 0.5)
+	}
+}
+
+// sample writes the RGBA color at texture coordinate u, v of mipmap level l
+// into dst.
+func (s *sampler) sample(dst []uint8, l *mipLevel, u, v float64) {
+	x := u * float64(l.w)
+	y := v * float64(l.h)
+
+	if s.filter == FilterNearest {
+		copy(dst, s.texel(l, int(math.Floor(x)), int(math.Floor(y))))
+		return
+	}
+
+	// texel centers sit at half integer coordinates
+	x -= 0.5
+	y -= 0.5
+	x0 := math.Floor(x)
+	y0 := math.Floor(y)
+	ax := x - x0
+	ay := y - y0
+
+	t00 := s.texel(l, int(x0), int(y0))
+	t10 := s.texel(l, int(x0)+1, int(y0))
+	t01 := s.texel(l, int(x0), int(y0)+1)
+	t11 := s.texel(l, int(x0)+1, int(y0)+1)
+
+	for c := 0; c < 4; c++ {
+		top := float64(t00[c])*(1-ax) + float64(t10[c])*ax
+		bottom := float64(t01[c])*(1-ax) + float64(t11[c])*ax
+		dst[c] = uint8(top*(1-ay) + bottom*ay + 0.5)
+	}
+}
+
+// texel returns the four RGBA bytes of texel x, y of mipmap level l after
+// applying the address mode. As in OBJ files y counts from the bottom of the
+// image.
+func (s *sampler) texel(l *mipLevel, x, y int) []uint8 {
+	x = addressTexel(s.address, x, l.w)
+	y = l.h - 1 - addressTexel(s.address, y, l.h)
+	o := (y*l.w + x) * 4
+	return l.pix[o : o+4]
+}
+
+// addressTexel maps i onto [0, n) according to mode.
+func addressTexel(mode AddressMode, i, n int) int {
+	switch mode {
+	case AddressMirroredRepeat:
+		period := 2 * n
+		i %= period
+		if i < 0 {
+			i += period
+		}
+		if i >= n {
+			i = period - 1 - i
+		}
+	case AddressClampToEdge:
+		i = max(0, min(i, n-1))
+	default:
+		i %= n
+		if i < 0 {
+			i += n
+		}
+	}
+	return i
+}
+
+// generateMipmaps builds the mipmap chain below the w x h RGBA image pix down
+// to a single texel, each level halving the size of the one above. Every
+// texel is the box filtered area of the level above that it covers, which
+// for odd sizes takes in parts of the texels that straddle two, so no row or
+// column is dropped.
+func generateMipmaps(pix []uint8, w, h int) []mipLevel {
+	levels := []mipLevel{{pix: pix, w: w, h: h}}
+	for w > 1 || h > 1 {
+		src := levels[len(levels)-1]
+		w = max(1, w/2)
+		h = max(1, h/2)
+		dst := mipLevel{pix: make([]uint8, w*h*4), w: w, h: h}
+
+		wx := boxWeights(src.w, w)
+		wy := boxWeights(src.h, h)
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				var sum [4]float64
+				for _, ty := range wy[y] {
+					for _, tx := range wx[x] {
+						o := (ty.i*src.w + tx.i) * 4
+						weight := tx.w * ty.w
+						for c := 0; c < 4; c++ {
+							sum[c] += float64(src.pix[o+c]) * weight
+						}
+					}
+				}
+				for c := 0; c < 4; c++ {
+					dst.pix[(y*w+x)*4+c] = uint8(sum[c] + 0.5)
+				}
+			}
+		}
+		levels = append(levels, dst)
+	}
+	return levels
+}
+
+// texelWeight is how much texel i of a row or column contributes to a texel
+// of the next smaller mipmap level.
+type texelWeight struct {
+	i int
+	w float64
+}
+
+// boxWeights returns for every one of n texels the texels of a row or
+// column src texels long that it covers, weighted by the covered part.
+func boxWeights(src, n int) [][]texelWeight {
+	scale := float64(src) / float64(n)
+	weights := make([][]texelWeight, n)
+	for i := range weights {
+		lo, hi := float64(i)*scale, float64(i+1)*scale
+		for j := int(lo); float64(j) < hi && j < src; j++ {
+			covered := math.Min(hi, float64(j+1)) - math.Max(lo, float64(j))
+			if covered > 0 {
+				weights[i] = append(weights[i], texelWeight{j, covered / scale})
+			}
+		}
+	}
+	return weights
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io/fs"
+	"math"
+	"testing"
+	"testing/fstest"
+)
+
+func encodedImages(t *testing.T) fstest.MapFS {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	for i := range img.Pix {
+		img.Pix[i] = 255
+	}
+
+	var pngData, jpegData, gifData bytes.Buffer
+	if err := png.Encode(&pngData, img); err != nil {
+		t.Fatal(err)
+	}
+	if err := jpeg.Encode(&jpegData, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := gif.Encode(&gifData, img, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	return fstest.MapFS{
+		"textures/a.png":   {Data: pngData.Bytes()},
+		"textures/a.jpg":   {Data: jpegData.Bytes()},
+		"textures/a.gif":   {Data: gifData.Bytes()},
+		"textures/bad.png": {Data: []byte("not an image")},
+	}
+}
+
+func TestTextureLoaderFormats(t *testing.T) {
+	loader := NewTextureLoader(encodedImages(t))
+
+	for _, name := range []string{"textures/a.png", "textures/a.jpg", "textures/a.gif"} {
+		tex, err := loader.Load(name)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if tex.W() != 4 || tex.H() != 2 {
+			t.Errorf("%s: got %dx%d, want 4x2", name, tex.W(), tex.H())
+		}
+		r, g, b, _ := tex.ColorAt(1, 1).RGBA()
+		if r < 0xf000 || g < 0xf000 || b < 0xf000 {
+			t.Errorf("%s: got color %v, want white", name, tex.ColorAt(1, 1))
+		}
+	}
+}
+
+func TestTextureLoaderCache(t *testing.T) {
+	loader := NewTextureLoader(encodedImages(t))
+
+	a, err := loader.Load("textures/a.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := loader.Load("textures/../textures/a.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != b {
+		t.Error("loading the same path twice returned different textures")
+	}
+
+	loader.Filter = FilterBilinear
+	c, err := loader.Load("textures/a.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c == a || c.Filter() != FilterBilinear {
+		t.Errorf("changed filter: got a texture with filter %v, want a new one with %v", c.Filter(), FilterBilinear)
+	}
+	if a.Filter() != FilterNearest {
+		t.Errorf("changed filter: the cached texture changed to %v", a.Filter())
+	}
+}
+
+func TestTextureLoaderErrors(t *testing.T) {
+	loader := NewTextureLoader(encodedImages(t))
+
+	if _, err := loader.Load("textures/missing.png"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("missing file: got %v, want fs.ErrNotExist", err)
+	}
+	if _, err := loader.Load("textures/bad.png"); !errors.Is(err, image.ErrFormat) {
+		t.Errorf("bad file: got %v, want image.ErrFormat", err)
+	}
+}
+
+func TestTextureLoaderOS(t *testing.T) {
+	tex, err := NewTextureLoader(nil).Load("wall.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tex.W() == 0 || tex.H() == 0 {
+		t.Errorf("got empty texture %dx%d", tex.W(), tex.H())
+	}
+}
+
+func TestLoadEmbeddedTexture(t *testing.T) {
+	tex := &TextureAtlasImpl{}
+	if err := tex.LoadTexture(); err != nil {
+		t.Fatal(err)
+	}
+	if tex.W() == 0 || tex.H() == 0 {
+		t.Errorf("got empty texture %dx%d", tex.W(), tex.H())
+	}
+}
+
+func TestAddressTexel(t *testing.T) {
+	tests := []struct {
+		mode AddressMode
+		in   []int
+		want []int
+	}{
+		{AddressRepeat, []int{-5, -4, -1, 0, 3, 4, 9}, []int{3, 0, 3, 0, 3, 0, 1}},
+		{AddressMirroredRepeat, []int{-5, -4, -1, 0, 3, 4, 5, 8, 9}, []int{3, 3, 0, 0, 3, 3, 2, 0, 1}},
+		{AddressClampToEdge, []int{-5, -1, 0, 3, 4, 9}, []int{0, 0, 0, 3, 3, 3}},
+	}
+
+	for _, test := range tests {
+		for i, in := range test.in {
+			if got := addressTexel(test.mode, in, 4); got != test.want[i] {
+				t.Errorf("mode %d, texel %d: got %d, want %d", test.mode, in, got, test.want[i])
+			}
+		}
+	}
+}
+
+// checkerSampler returns a sampler over a 2x2 texture with a black and a
+// white texel in the bottom row and the same swapped in the top row.
+func checkerSampler(filter TextureFilter, address AddressMode) sampler {
+	pix := []uint8{
+		255, 255, 255, 255, 0, 0, 0, 255,
+		0, 0, 0, 255, 255, 255, 255, 255,
+	}
+	return sampler{
+		levels:  []mipLevel{{pix: pix, w: 2, h: 2}},
+		filter:  filter,
+		address: address,
+	}
+}
+
+func TestSampleNearest(t *testing.T) {
+	tests := []struct {
+		address AddressMode
+		u, v    float64
+		want    uint8
+	}{
+		{AddressRepeat, 0.25, 0.25, 0},
+		{AddressRepeat, 0.75, 0.25, 255},
+		{AddressRepeat, 0.25, 0.75, 255},
+		{AddressRepeat, 1.25, 0.25, 0},
+		{AddressRepeat, -0.25, 0.25, 255},
+		{AddressMirroredRepeat, 1.25, 0.25, 255},
+		{AddressClampToEdge, 7, 0.25, 255},
+		{AddressClampToEdge, -7, 0.25, 0},
+	}
+
+	for _, test := range tests {
+		s := checkerSampler(FilterNearest, test.address)
+		dst := make([]uint8, 4)
+		s.sample(dst, &s.levels[0], test.u, test.v)
+		if dst[0] != test.want || dst[3] != 255 {
+			t.Errorf("address %d at %v,%v: got %v, want %d", test.address, test.u, test.v, dst, test.want)
+		}
+	}
+}
+
+func TestSampleBilinear(t *testing.T) {
+	tests := []struct {
+		address AddressMode
+		u, v    float64
+		want    uint8
+	}{
+		// texel centers return the texel itself
+		{AddressClampToEdge, 0.25, 0.25, 0},
+		{AddressClampToEdge, 0.75, 0.25, 255},
+		// halfway between a black and a white texel
+		{AddressClampToEdge, 0.5, 0.25, 128},
+		// the middle of the texture blends all four
+		{AddressClampToEdge, 0.5, 0.5, 128},
+		// at the edge clamping keeps the edge texel, repeating blends
+		// with the opposite side
+		{AddressClampToEdge, 0, 0.25, 0},
+		{AddressRepeat, 0, 0.25, 128},
+	}
+
+	for _, test := range tests {
+		s := checkerSampler(FilterBilinear, test.address)
+		dst := make([]uint8, 4)
+		s.sample(dst, &s.levels[0], test.u, test.v)
+		if dst[0] != test.want || dst[3] != 255 {
+			t.Errorf("address %d at %v,%v: got %v, want %d", test.address, test.u, test.v, dst, test.want)
+		}
+	}
+}
+
+func TestGenerateMipmaps(t *testing.T) {
+	// a 5x3 texture whose texels are all 100 except one 200
+	pix := make([]uint8, 5*3*4)
+	for i := range pix {
+		pix[i] = 100
+	}
+	pix[0] = 200
+
+	levels := generateMipmaps(pix, 5, 3)
+
+	sizes := [][2]int{{5, 3}, {2, 1}, {1, 1}}
+	if len(levels) != len(sizes) {
+		t.Fatalf("got %d levels, want %d", len(levels), len(sizes))
+	}
+	for i, size := range sizes {
+		if levels[i].w != size[0] || levels[i].h != size[1] || len(levels[i].pix) != size[0]*size[1]*4 {
+			t.Errorf("level %d: got %dx%d, want %dx%d", i, levels[i].w, levels[i].h, size[0], size[1])
+		}
+	}
+	// the first texel covers 2.5 x 3 texels above, 0.4 x 1/3 of them the 200
+	if got := levels[1].pix[0]; got != 113 {
+		t.Errorf("got averaged texel %d, want 113", got)
+	}
+	if got := levels[1].pix[4]; got != 100 {
+		t.Errorf("got texel %d, want 100", got)
+	}
+}
+
+func TestGenerateMipmapsKeepsEdges(t *testing.T) {
+	// odd sizes must not drop the last row or column, so every level keeps
+	// the average of the texture
+	const w, h = 7, 5
+	pix := make([]uint8, w*h*4)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			o := (y*w + x) * 4
+			pix[o] = uint8(x * 40)
+			pix[o+1] = uint8(y * 60)
+		}
+	}
+
+	mean := func(l mipLevel, c int) float64 {
+		sum := 0.0
+		for i := c; i < len(l.pix); i += 4 {
+			sum += float64(l.pix[i])
+		}
+		return sum / float64(l.w*l.h)
+	}
+
+	levels := generateMipmaps(pix, w, h)
+	for i, l := range levels[1:] {
+		for c := 0; c < 2; c++ {
+			if got, want := mean(l, c), mean(levels[0], c); math.Abs(got-want) > 1 {
+				t.Errorf("level %d channel %d: got mean %g, want %g", i+1, c, got, want)
+			}
+		}
+	}
+}
+
+func TestSampleLod(t *testing.T) {
+	// level 0 is black, level 1 white
+	s := sampler{
+		levels: []mipLevel{
+			{pix: []uint8{0, 0, 0, 255, 0, 0, 0, 255, 0, 0, 0, 255, 0, 0, 0, 255}, w: 2, h: 2},
+			{pix: []uint8{255, 255, 255, 255}, w: 1, h: 1},
+		},
+		filter:  FilterBilinear,
+		address: AddressRepeat,
+	}
+
+	tests := []struct {
+		mipmap MipmapFilter
+		lod    float64
+		want   uint8
+	}{
+		{MipmapNearest, -3, 0},
+		{MipmapNearest, 0.4, 0},
+		{MipmapNearest, 0.6, 255},
+		{MipmapNearest, 5, 255},
+		{MipmapLinear, 0, 0},
+		{MipmapLinear, 0.5, 128},
+		{MipmapLinear, 0.25, 64},
+		{MipmapLinear, 5, 255},
+	}
+
+	for _, test := range tests {
+		s.mipmap = test.mipmap
+		dst := make([]uint8, 4)
+		s.sampleLod(dst, 0.5, 0.5, test.lod)
+		if dst[0] != test.want {
+			t.Errorf("mipmap %d at lod %v: got %d, want %d", test.mipmap, test.lod, dst[0], test.want)
+		}
+	}
+}
+
+func TestSampleProjectedLod(t *testing.T) {
+	tex := newTexture(image.NewRGBA(image.Rect(0, 0, 64, 64)))
+	tex.SetMipmapFilter(MipmapNearest)
+	s := newSampler(tex)
+	// mark every level with its index
+	for i := range s.levels {
+		s.levels[i].pix = bytes.Repeat([]uint8{uint8(i), 0, 0, 255}, s.levels[i].w*s.levels[i].h)
+	}
+
+	tests := []struct {
+		// texels covered per pixel, with w = 1 and no perspective
+		step float64
+		want uint8
+	}{
+		{0.5, 0},
+		{1, 0},
+		{2, 1},
+		{4, 2},
+		{64, 6},
+		{256, 6},
+	}
+
+	for _, test := range tests {
+		g := uvGradients{dudx: test.step / 64, dvdy: test.step / 64}
+		dst := make([]uint8, 4)
+		s.sampleProjected(dst, 0.5, 0.5, 1, &g)
+		if dst[0] != test.want {
+			t.Errorf("step %v: got level %d, want %d", test.step, dst[0], test.want)
+		}
+	}
+}
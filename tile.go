@@ -0,0 +1,80 @@
+package main
+
+import (
+	"image"
+	"math"
+)
+
+// tileSize is the edge length in pixels of the square tiles the screen is
+// split into for parallel rasterization.
+const tileSize = 64
+
+// tile is a rectangle of the framebuffer together with the triangles that
+// overlap it. Only the worker rasterizing a tile writes to its pixels, so
+// tiles need no locking.
+type tile struct {
+	rect image.Rectangle
+	// tris indexes Renderer.rasterList in submission order
+	tris []int32
+}
+
+func makeTiles(w, h int) []tile {
+	var tiles []tile
+	for y := 0; y < h; y += tileSize {
+		for x := 0; x < w; x += tileSize {
+			tiles = append(tiles, tile{
+				rect: image.Rect(x, y, min(x+tileSize, w), min(y+tileSize, h)),
+			})
+		}
+	}
+	return tiles
+}
+
+// rasterizeTiles bins the triangles of the raster list into the tiles their
+// bounding boxes overlap and rasterizes the tiles on r.workers goroutines of
+// the worker pool. Every tile draws its triangles in submission order, so the
+// result is the same as rasterizing the whole list on one goroutine.
+func (r *Renderer) rasterizeTiles() {
+	tilesX := (r.w + tileSize - 1) / tileSize
+	tilesY := (r.h + tileSize - 1) / tileSize
+
+	for i := range r.tiles {
+		r.tiles[i].tris = r.tiles[i].tris[:0]
+	}
+
+	for i := range r.rasterList {
+		t := &r.rasterList[i].t
+		minX := math.Min(t.p[0].x, math.Min(t.p[1].x, t.p[2].x))
+		maxX := math.Max(t.p[0].x, math.Max(t.p[1].x, t.p[2].x))
+		minY := math.Min(t.p[0].y, math.Min(t.p[1].y, t.p[2].y))
+		maxY := math.Max(t.p[0].y, math.Max(t.p[1].y, t.p[2].y))
+
+		tx0 := max(0, int(minX)/tileSize)
+		tx1 := min(tilesX-1, int(maxX)/tileSize)
+		ty0 := max(0, int(minY)/tileSize)
+		ty1 := min(tilesY-1, int(maxY)/tileSize)
+
+		for ty := ty0; ty <= ty1; ty++ {
+			for tx := tx0; tx <= tx1; tx++ {
+				bin := &r.tiles[ty*tilesX+tx]
+				bin.tris = append(bin.tris, int32(i))
+			}
+		}
+	}
+
+	pool.run(r, stageTiles, min(r.workers, len(r.tiles)))
+}
+
+// tileWorker rasterizes tiles until none are left.
+func (r *Renderer) tileWorker() {
+	for {
+		i := int(r.next.Add(1)) - 1
+		if i >= len(r.tiles) {
+			return
+		}
+		tile := &r.tiles[i]
+		for _, t := range tile.tris {
+			r.rasterize(&r.rasterList[t], tile.rect)
+		}
+	}
+}
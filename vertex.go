@@ -0,0 +1,115 @@
+package main
+
+// vertexChunkSize is the number of mesh vertices or triangles a worker
+// processes in one go during the parallel vertex stage.
+const vertexChunkSize = 512
+
+// transformedVertex is a mesh vertex's position in world and view space and
+// its normal in world space, cached for all triangles sharing the vertex.
+type transformedVertex struct {
+	world, view vec3d
+	normal      vec3d
+}
+
+// processVertices runs the vertex stage for m and leaves the projected
+// triangles in r.trianglesToRaster. Every vertex of m is transformed once
+// into r.vertexCache, then its triangles are assembled from the cache,
+// culled, clipped and projected.
+//
+// With more than one worker both steps are split into chunks that are
+// processed in parallel. Projected triangles go to per-chunk buffers, which
+// are then concatenated in chunk order, so the result is the same as
+// processing the whole mesh on one goroutine.
+func (r *Renderer) processVertices(m *mesh, matWorld, matView *mat4x4, vCamera *vec3d) {
+	r.trianglesToRaster = r.trianglesToRaster[:0]
+
+	if cap(r.vertexCache) < len(m.vertices) {
+		r.vertexCache = make([]transformedVertex, len(m.vertices))
+	}
+	r.vertexCache = r.vertexCache[:len(m.vertices)]
+
+	// a world transform that flattens the mesh has no normal matrix, its
+	// faces are edge on anyway
+	matNormal := &r.matNormal
+	var err error
+	if *matNormal, err = matWorld.normalMatrix(); err != nil {
+		*matNormal = *matWorld
+	}
+
+	nTriangles := m.triangles()
+	nChunks := (nTriangles + vertexChunkSize - 1) / vertexChunkSize
+	if r.workers <= 1 || nChunks <= 1 {
+		r.transformVertices(m.vertices, 0, matWorld, matView, matNormal)
+		for i := 0; i < nTriangles; i++ {
+			r.trianglesToRaster = r.projectTriangle(m, i, vCamera, &r.clipQueue, r.trianglesToRaster)
+		}
+		return
+	}
+
+	for len(r.vertexChunks) < nChunks {
+		r.vertexChunks = append(r.vertexChunks, nil)
+	}
+
+	r.vertexInput = vertexInput{m, matWorld, matView, matNormal, vCamera}
+	vertexChunks := (len(m.vertices) + vertexChunkSize - 1) / vertexChunkSize
+	pool.run(r, stageTransform, min(r.workers, vertexChunks))
+	pool.run(r, stageVertices, min(r.workers, nChunks))
+	r.vertexInput = vertexInput{}
+
+	for _, out := range r.vertexChunks[:nChunks] {
+		r.trianglesToRaster = append(r.trianglesToRaster, out...)
+	}
+}
+
+// transformVertices transforms vertices, which start at index first of the
+// mesh, into world and view space, and their normals by matNormal.
+func (r *Renderer) transformVertices(vertices []vertex, first int, matWorld, matView, matNormal *mat4x4) {
+	for i := range vertices {
+		c := &r.vertexCache[first+i]
+		c.world = matWorld.matrixMultiplyVector(&vertices[i].p)
+		c.view = matView.matrixMultiplyVector(&c.world)
+		c.normal = matNormal.matrixMultiplyVector(&vertices[i].n)
+	}
+}
+
+// vertexInput is what the workers of the parallel vertex stage process.
+type vertexInput struct {
+	m                            *mesh
+	matWorld, matView, matNormal *mat4x4
+	vCamera                      *vec3d
+}
+
+// transformWorker transforms chunks of the vertices of r.vertexInput until
+// none are left.
+func (r *Renderer) transformWorker() {
+	in := &r.vertexInput
+	vertices := in.m.vertices
+	for {
+		first := (int(r.next.Add(1)) - 1) * vertexChunkSize
+		if first >= len(vertices) {
+			return
+		}
+		chunk := vertices[first:min(first+vertexChunkSize, len(vertices))]
+		r.transformVertices(chunk, first, in.matWorld, in.matView, in.matNormal)
+	}
+}
+
+// vertexWorker projects chunks of the triangles of r.vertexInput until none
+// are left.
+func (r *Renderer) vertexWorker() {
+	in := &r.vertexInput
+	nTriangles := in.m.triangles()
+	var queue triangleQueue
+	for {
+		c := int(r.next.Add(1)) - 1
+		first := c * vertexChunkSize
+		if first >= nTriangles {
+			return
+		}
+		out := r.vertexChunks[c][:0]
+		for i := first; i < min(first+vertexChunkSize, nTriangles); i++ {
+			out = r.projectTriangle(in.m, i, in.vCamera, &queue, out)
+		}
+		r.vertexChunks[c] = out
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestParallelVerticesMatchSerial(t *testing.T) {
+	for _, name := range []string{"teapot.obj", "mountains.obj"} {
+		t.Run(name, func(t *testing.T) {
+			var m mesh
+			if err := m.Load(name, NewTextureLoader(nil)); err != nil {
+				t.Fatal(err)
+			}
+			if m.triangles() <= vertexChunkSize {
+				t.Fatalf("%s has %d triangles, want more than one chunk", name, m.triangles())
+			}
+
+			r := NewRenderer(256, 256, defaultProjection(256, 256, 90))
+			matWorld := matrixMakeIdentity()
+			vCamera := vec3d{1, 2, -7, 1}
+			vLookDirection := lookDirection(0.15)
+			matView := matrixMakeView(&vCamera, &vLookDirection)
+
+			r.SetWorkers(1)
+			r.processVertices(&m, &matWorld, &matView, &vCamera)
+			serial := append([]triangle(nil), r.trianglesToRaster...)
+
+			r.SetWorkers(4)
+			r.processVertices(&m, &matWorld, &matView, &vCamera)
+			if !reflect.DeepEqual(r.trianglesToRaster, serial) {
+				t.Errorf("parallel vertex stage produced %d triangles differing from the serial %d",
+					len(r.trianglesToRaster), len(serial))
+			}
+		})
+	}
+}
+
+func TestVertexCache(t *testing.T) {
+	var m mesh
+	m.LoadCube()
+
+	r := NewRenderer(64, 64, defaultProjection(64, 64, 90))
+	matWorld := matrixMakeTranslation(1, 2, 3)
+	vCamera := vec3d{0.5, 0.5, -4, 1}
+	vLookDirection := lookDirection(0)
+	matView := matrixMakeView(&vCamera, &vLookDirection)
+	r.processVertices(&m, &matWorld, &matView, &vCamera)
+
+	if len(r.vertexCache) != len(m.vertices) {
+		t.Fatalf("got %d cached vertices, want %d", len(r.vertexCache), len(m.vertices))
+	}
+	for i, v := range m.vertices {
+		world := matWorld.matrixMultiplyVector(&v.p)
+		view := matView.matrixMultiplyVector(&world)
+		if c := r.vertexCache[i]; c.world != world || c.view != view {
+			t.Errorf("vertex %d: got %v, want world %v and view %v", i, c, world, view)
+		}
+	}
+}
+
+func TestVaryingsSurviveClipping(t *testing.T) {
+	var m mesh
+	m.LoadCube()
+	m.vertices[2].c = [4]float64{0, 0, 1, 1}
+
+	r := NewRenderer(64, 64, defaultProjection(64, 64, 90))
+	matWorld := matrixMakeTranslation(1, 2, 3)
+	// close enough to the quad that its corners are off screen
+	vCamera := vec3d{1.5, 2.5, 2.8, 1}
+	vLookDirection := lookDirection(0)
+	matView := matrixMakeView(&vCamera, &vLookDirection)
+	r.processVertices(&m, &matWorld, &matView, &vCamera)
+
+	if len(r.trianglesToRaster) <= m.triangles() {
+		t.Fatalf("got %d triangles, want the quad to be clipped", len(r.trianglesToRaster))
+	}
+	for _, tri := range r.trianglesToRaster {
+		for n := range tri.v {
+			world := tri.v[n].vec3(varyingWorld)
+			if math.Abs(world.z-3) > 1e-9 || world.x < 1 || world.x > 2 || world.y < 2 || world.y > 3 {
+				t.Errorf("world position %v is not on the quad", world)
+			}
+			// one corner is blue, so red and green fade out towards it
+			c := tri.v[n].color()
+			if math.Abs(c[0]-c[1]) > 1e-9 || c[0] < 0 || c[0] > 1 || c[2] != 1 || c[3] != 1 {
+				t.Errorf("got color %v, want a blend of white and blue", c)
+			}
+		}
+	}
+}
+
+func TestGuardBandReducesTriangles(t *testing.T) {
+	var m mesh
+	if err := m.Load("Level1.obj", NewTextureLoader(nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewRenderer(256, 256, defaultProjection(256, 256, 90))
+	matWorld := matrixMakeIdentity()
+	vCamera := vec3d{0, 8, -40, 1}
+	vLookDirection := lookDirection(0)
+	matView := matrixMakeView(&vCamera, &vLookDirection)
+
+	r.processVertices(&m, &matWorld, &matView, &vCamera)
+	clipped := len(r.trianglesToRaster)
+
+	r.SetGuardBand(defaultGuardBand)
+	r.processVertices(&m, &matWorld, &matView, &vCamera)
+	guarded := len(r.trianglesToRaster)
+
+	t.Logf("%d triangles with screen edge clipping, %d with a %dx guard band", clipped, guarded, defaultGuardBand)
+	if guarded >= clipped {
+		t.Errorf("guard band left %d triangles, want fewer than %d", guarded, clipped)
+	}
+}